@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+// SetupAdminMiddleware only lets through the requests carrying the ADMIN_TOKEN in the
+// X-Admin-Token header. The admin API is disabled when no token is configured.
+func SetupAdminMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token := os.Getenv("ADMIN_TOKEN")
+			if token == "" {
+				return echo.NewHTTPError(http.StatusForbidden, "admin API disabled")
+			}
+			given := c.Request().Header.Get("X-Admin-Token")
+			if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				return echo.NewHTTPError(http.StatusUnauthorized, "invalid admin token")
+			}
+			return next(c)
+		}
+	}
+}
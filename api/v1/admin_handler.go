@@ -0,0 +1,60 @@
+package v1
+
+import (
+	"expvar"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/thesrcielos/TopTankBattle/internal/game"
+)
+
+var InstanceRegistry game.InstanceRegistry
+
+var GameService *game.GameServiceImpl
+
+func RegisterAdminRoutes(g *echo.Group) {
+	g.GET("/instances", GetInstancesHandler)
+	g.GET("/drain", GetDrainHandler)
+	g.POST("/drain", DrainHandler)
+	g.DELETE("/drain", ResumeHandler)
+	g.GET("/metrics", echo.WrapHandler(expvar.Handler()))
+}
+
+func GetInstancesHandler(c echo.Context) error {
+	instances, err := InstanceRegistry.ListInstances()
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"self":      game.InstanceID(),
+		"instances": instances,
+	})
+}
+
+func GetDrainHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, echo.Map{
+		"draining": GameService.IsDraining(),
+		"rooms":    GameService.LedRooms(),
+	})
+}
+
+// DrainHandler puts the instance in drain mode, with handoff=true its games move to other instances
+func DrainHandler(c echo.Context) error {
+	handOff := c.QueryParam("handoff") == "true"
+	handedOff := GameService.Drain(handOff)
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"draining": true,
+		"handoff":  handedOff,
+		"rooms":    GameService.LedRooms(),
+	})
+}
+
+func ResumeHandler(c echo.Context) error {
+	GameService.Resume()
+
+	return c.JSON(http.StatusOK, echo.Map{
+		"draining": false,
+	})
+}
@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -16,9 +19,12 @@ import (
 	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game"
 	"github.com/thesrcielos/TopTankBattle/internal/game/maps"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
 	"github.com/thesrcielos/TopTankBattle/internal/user"
+	"github.com/thesrcielos/TopTankBattle/pkg/config"
 	"github.com/thesrcielos/TopTankBattle/pkg/db"
 	"github.com/thesrcielos/TopTankBattle/websocket"
+	"github.com/thesrcielos/TopTankBattle/websocket/actions"
 )
 
 func main() {
@@ -27,11 +33,19 @@ func main() {
 		log.Println("⚠️File .env not found, using system values")
 	}
 
-	db.Init()
-	db.DB.AutoMigrate(&user.User{})
-	db.DB.AutoMigrate(&user.UserStats{})
+	memoryStorage := config.UseMemoryStorage()
+	if memoryStorage {
+		log.Println("Using in-memory storage, data is lost on restart")
+	} else {
+		if os.Getenv("INSTANCE_ID") == "" {
+			log.Fatal("INSTANCE_ID must be set to a stable id of the instance, it names its consumer group of the Redis streams")
+		}
+		db.Init()
+		db.DB.AutoMigrate(&user.User{})
+		db.DB.AutoMigrate(&user.UserStats{})
+	}
 	maps.GenerateCollisionMatrix("map.json")
-	inyectDependencies()
+	gameService, bus, heartbeat := inyectDependencies(memoryStorage)
 	e := echo.New()
 
 	e.HTTPErrorHandler = func(err error, c echo.Context) {
@@ -63,42 +77,139 @@ func main() {
 	g.Use(api_middleware.SetupJWTMiddleware())
 	v1.RegisterRoomRoutes(g)
 
+	admin := api.Group("/admin")
+	admin.Use(api_middleware.SetupAdminMiddleware())
+	v1.RegisterAdminRoutes(admin)
+
 	e.GET("/game", websocket.WebSocketHandler)
 	e.GET("/deleteAll", func(c echo.Context) error {
-		if err := db.Rdb.FlushAll(context.Background()).Err(); err != nil {
+		if db.Rdb == nil {
+			return echo.NewHTTPError(http.StatusNotImplemented, "Not available with in-memory storage")
+		}
+		if err := deleteServerKeys(context.Background()); err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to delete all data")
 		}
 		return c.JSON(http.StatusOK, echo.Map{"message": "All data deleted successfully"})
 	})
 	e.GET("/health", func(c echo.Context) error {
-		return c.JSON(http.StatusOK, echo.Map{"ok": true})
+		health := bus.Health()
+		draining := gameService.IsDraining()
+		ready := health.Connected && !draining
+		status := http.StatusOK
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		return c.JSON(status, echo.Map{"ok": ready, "bus": health, "draining": draining})
 	})
-	e.Logger.Fatal(e.Start(":8080"))
+	go func() {
+		if err := e.Start(":8080"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	<-quit
 
+	log.Println("Shutting down, handing off led games...")
+	gameService.Shutdown()
+	heartbeat.Stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		e.Logger.Fatal(err)
+	}
 }
 
-func inyectDependencies() {
+func inyectDependencies(memoryStorage bool) (*game.GameServiceImpl, game.MessageBus, *game.Heartbeat) {
 	var gameServiceImp *game.GameServiceImpl
-	redisRepository := game.NewGameStateRepository(gameServiceImp, db.Rdb)
-	userRepository := user.NewUserRepository(db.DB)
-	roomRepository := game.NewRedisRoomRepository(userRepository, db.Rdb)
+	var redisRepository game.GameStateRepository
+	var roomRepository game.RoomRepository
+	var userRepository user.UserRepository
+	var registry game.InstanceRegistry
+	var chatRepository game.ChatRepository
+	bus := newMessageBus(memoryStorage)
+	if memoryStorage {
+		registry = game.NewMemoryInstanceRegistry()
+		userRepository = user.NewMemoryUserRepository()
+		redisRepository = game.NewMemoryGameStateRepository(gameServiceImp, bus)
+		memoryRooms := game.NewMemoryRoomRepository(userRepository, bus)
+		memoryChat := game.NewMemoryChatRepository()
+		memoryRooms.SetChatRepository(memoryChat)
+		roomRepository = memoryRooms
+		chatRepository = memoryChat
+	} else {
+		registry = game.NewRedisInstanceRegistry(db.Rdb)
+		userRepository = user.NewUserRepository(db.DB)
+		redisRepository = game.NewGameStateRepository(gameServiceImp, db.Rdb, bus)
+		roomRepository = game.NewRedisRoomRepository(userRepository, db.Rdb, bus)
+		chatRepository = game.NewRedisChatRepository(db.Rdb)
+	}
 	roomService := game.NewRoomService(roomRepository)
 	userService := user.NewUserService(userRepository)
+	chatService := game.NewChatService(chatRepository, roomRepository)
 	gameServiceImp = game.NewGameService(redisRepository, roomRepository, roomService, userService)
 	redisRepository.SetLeaderElector(gameServiceImp)
+	gameServiceImp.SetInstanceRegistry(registry)
+	state.SetRoomListener(gameServiceImp)
+	state.SetInstance(game.InstanceID())
 	v1.RoomService = roomService
 	v1.UserService = userService
+	v1.InstanceRegistry = registry
+	v1.GameService = gameServiceImp
 	websocket.RoomService = roomService
+	actions.RoomService = roomService
+	actions.ChatService = chatService
+	websocket.ChatService = chatService
 	websocket.GameService = gameServiceImp
+	leaderLocator := game.NewLeaderLocator(redisRepository, registry)
+	redisRepository.SetLeaderLocator(leaderLocator)
+	websocket.LeaderLocator = leaderLocator
+
+	game.NewJanitor(roomService, roomRepository, redisRepository, registry, bus).Start(janitorInterval())
+	heartbeat := game.NewHeartbeat(registry, gameServiceImp)
+	heartbeat.Start()
+	return gameServiceImp, bus, heartbeat
+}
 
-	startRedisSubscriber(redisRepository)
+// deleteServerKeys removes only the keys of the server, other applications may share the database
+func deleteServerKeys(ctx context.Context) error {
+	iter := db.Rdb.Scan(ctx, 0, db.KeyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := db.Rdb.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// janitorInterval is the time between sweeps of orphaned rooms, set with JANITOR_INTERVAL
+func janitorInterval() time.Duration {
+	if interval, err := time.ParseDuration(os.Getenv("JANITOR_INTERVAL")); err == nil && interval > 0 {
+		return interval
+	}
+	return 1 * time.Minute
+}
+
+// newMessageBus selects the bus used between instances, MESSAGE_BUS=memory keeps every
+// event inside this process for single node deployments
+func newMessageBus(memoryStorage bool) game.MessageBus {
+	if memoryStorage || os.Getenv("MESSAGE_BUS") == "memory" {
+		log.Println("Using in-memory message bus")
+		return game.NewMemoryMessageBus()
+	}
+	bus := game.NewRedisMessageBus(db.Rdb)
+	startRedisSubscriber(bus)
+	return bus
 }
 
-func startRedisSubscriber(repo game.GameStateRepository) {
+// startRedisSubscriber connects the bus, from then on it supervises and recovers its own reads
+func startRedisSubscriber(bus *game.RedisMessageBus) {
 	go func() {
 		for {
 			log.Println("Intentando suscribirse al canal Redis...")
-			err := repo.SubscribeMessages()
+			err := bus.Connect()
 			if err != nil {
 				log.Printf("Fallo al suscribirse: %v", err)
 				time.Sleep(1 * time.Second)
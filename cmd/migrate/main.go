@@ -0,0 +1,188 @@
+// Command migrate moves the data written before the ttb: key schema to the namespaced keys.
+// It must run once with every server instance stopped: running games cannot be resumed,
+// so leaderships, game hashes and connection marks are dropped.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/redis/go-redis/v9"
+	"github.com/thesrcielos/TopTankBattle/pkg/db"
+)
+
+var ctx = context.Background()
+
+type migration struct {
+	rdb    *redis.Client
+	dryRun bool
+	rooms  map[string]bool
+	moved  int
+	erased int
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "print the changes without applying them")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️File .env not found, using system values")
+	}
+	db.InitRedis()
+
+	m := &migration{rdb: db.Rdb, dryRun: *dryRun, rooms: make(map[string]bool)}
+	if err := m.run(); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+	log.Printf("Migration finished: %d keys moved, %d keys deleted (dry run: %t)", m.moved, m.erased, m.dryRun)
+}
+
+func (m *migration) run() error {
+	if err := m.migrateRooms(); err != nil {
+		return err
+	}
+	if err := m.migratePlayerRooms(); err != nil {
+		return err
+	}
+	for _, pattern := range []string{"room:*:player:*", "room:*:fortress:*", "room:*:bullet:*"} {
+		if err := m.scan(pattern, m.deleteType("hash")); err != nil {
+			return err
+		}
+	}
+	for _, pattern := range []string{"leader:*", "ws:*"} {
+		if err := m.scan(pattern, m.deleteType("string")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateRooms moves the room documents and the rooms_id sorted set
+func (m *migration) migrateRooms() error {
+	rooms, err := m.rdb.ZRangeWithScores(ctx, "rooms_id", 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	for _, room := range rooms {
+		roomID := room.Member.(string)
+		m.rooms[roomID] = true
+		if err := m.rename(roomID, db.RoomKey(roomID)); err != nil {
+			return err
+		}
+		log.Printf("index room %s in %s", roomID, db.RoomsKey)
+		if !m.dryRun {
+			if err := m.rdb.ZAdd(ctx, db.RoomsKey, redis.Z{Score: room.Score, Member: roomID}).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return m.delete("rooms_id")
+}
+
+// migratePlayerRooms moves the player mappings, stored under bare numeric user ids. Only the
+// keys holding the id of a migrated room are moved, other numeric keys may belong to other
+// applications sharing the database
+func (m *migration) migratePlayerRooms() error {
+	return m.scan("[0-9]*", func(key string) error {
+		if _, err := strconv.Atoi(key); err != nil || m.rooms[key] {
+			return nil
+		}
+		if keyType, err := m.rdb.Type(ctx, key).Result(); err != nil || keyType != "string" {
+			return err
+		}
+		roomID, err := m.rdb.Get(ctx, key).Result()
+		if err == redis.Nil || !m.rooms[roomID] {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := m.rename(key, db.PlayerRoomKey(key)); err != nil {
+			return err
+		}
+		log.Printf("index player %s in %s", key, db.PlayerRoomsKey)
+		if m.dryRun {
+			return nil
+		}
+		return m.rdb.SAdd(ctx, db.PlayerRoomsKey, key).Err()
+	})
+}
+
+// scan calls fn for every key matching the pattern without blocking Redis like KEYS
+func (m *migration) scan(pattern string, fn func(key string) error) error {
+	iter := m.rdb.Scan(ctx, 0, pattern, 100).Iterator()
+	keys := []string{}
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := fn(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rename moves a key unless it is missing or the new key already exists
+func (m *migration) rename(from, to string) error {
+	if m.dryRun {
+		exists, err := m.rdb.Exists(ctx, from).Result()
+		if err != nil || exists == 0 {
+			return err
+		}
+		log.Printf("move %s -> %s", from, to)
+		m.moved++
+		return nil
+	}
+
+	renamed, err := m.rdb.RenameNX(ctx, from, to).Result()
+	if err != nil && err.Error() == "ERR no such key" {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !renamed {
+		log.Printf("skip %s: %s already exists", from, to)
+		return nil
+	}
+	log.Printf("move %s -> %s", from, to)
+	m.moved++
+	return nil
+}
+
+// deleteType deletes the keys of the type the old schema wrote, leaving alone the keys of
+// other applications that happen to match the same pattern
+func (m *migration) deleteType(keyType string) func(key string) error {
+	return func(key string) error {
+		actual, err := m.rdb.Type(ctx, key).Result()
+		if err != nil || actual != keyType {
+			return err
+		}
+		return m.delete(key)
+	}
+}
+
+func (m *migration) delete(key string) error {
+	if m.dryRun {
+		exists, err := m.rdb.Exists(ctx, key).Result()
+		if err != nil || exists == 0 {
+			return err
+		}
+		log.Printf("delete %s", key)
+		m.erased++
+		return nil
+	}
+
+	deleted, err := m.rdb.Del(ctx, key).Result()
+	if err != nil || deleted == 0 {
+		return err
+	}
+	log.Printf("delete %s", key)
+	m.erased++
+	return nil
+}
@@ -0,0 +1,161 @@
+package game
+
+import (
+	"encoding/json"
+	"log"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+)
+
+// maxChatLength is the longest chat message in characters
+const maxChatLength = 200
+
+// Chat channels, team messages only reach the team of the sender
+const (
+	ChatChannelAll  = "all"
+	ChatChannelTeam = "team"
+)
+
+// ChatMessage is a message of a room chat. Team messages keep the players of the team when it
+// was sent, so players who switch teams later don't read the history of their new team.
+type ChatMessage struct {
+	ID         string   `json:"id"`
+	PlayerId   string   `json:"playerId"`
+	Username   string   `json:"username"`
+	Channel    string   `json:"channel"`
+	Team       int      `json:"team"`
+	Text       string   `json:"text"`
+	SentAt     int64    `json:"sentAt"`
+	Recipients []string `json:"recipients,omitempty"`
+}
+
+// ChatFilter rewrites the text of a message before it is sent, e.g. to mask profanity
+type ChatFilter func(text string) string
+
+type ChatService struct {
+	repo     ChatRepository
+	roomRepo RoomRepository
+	filter   ChatFilter
+}
+
+func NewChatService(repo ChatRepository, roomRepo RoomRepository) *ChatService {
+	return &ChatService{
+		repo:     repo,
+		roomRepo: roomRepo,
+		filter:   func(text string) string { return text },
+	}
+}
+
+// SetFilter sets the filter applied to every message
+func (c *ChatService) SetFilter(filter ChatFilter) {
+	c.filter = filter
+}
+
+// SendMessage sends a chat message of a player to its room or team, through the room events
+// so it reaches the players connected to other instances, and keeps it in the room history
+func (c *ChatService) SendMessage(playerId string, channel string, text string) (*ChatMessage, error) {
+	if channel == "" {
+		channel = ChatChannelAll
+	}
+	if channel != ChatChannelAll && channel != ChatChannelTeam {
+		return nil, apperrors.NewAppError(400, "Unknown chat channel "+channel, nil)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, apperrors.NewAppError(400, "Chat message is empty", nil)
+	}
+	if utf8.RuneCountInString(text) > maxChatLength {
+		return nil, apperrors.NewAppError(400, "Chat message is too long", nil)
+	}
+
+	room, err := c.playerRoom(playerId)
+	if err != nil {
+		return nil, err
+	}
+	sender, team := playerTeam(room, playerId)
+	if team == 0 {
+		return nil, apperrors.NewAppError(404, "Player not found in room", nil)
+	}
+
+	users := []string{}
+	for i, players := range [][]Player{room.Team1, room.Team2} {
+		if channel == ChatChannelTeam && i+1 != team {
+			continue
+		}
+		for _, player := range players {
+			users = append(users, player.ID)
+		}
+	}
+
+	message := ChatMessage{
+		ID:       uuid.NewString(),
+		PlayerId: playerId,
+		Username: sender.Username,
+		Channel:  channel,
+		Team:     team,
+		Text:     c.filter(text),
+		SentAt:   time.Now().UnixMilli(),
+	}
+	if channel == ChatChannelTeam {
+		message.Recipients = users
+	}
+	if err := c.repo.AppendMessage(room.ID, message); err != nil {
+		log.Println("Error saving chat message:", err)
+	}
+
+	data, err := json.Marshal(GameMessage{
+		Type:    "CHAT",
+		Payload: message,
+		Users:   users,
+	})
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error encoding chat message", err)
+	}
+	c.roomRepo.PublishToRoom(room.ID, string(data))
+	return &message, nil
+}
+
+// History returns the recent messages of a room a player can read, the team messages sent to
+// another team are left out
+func (c *ChatService) History(roomId string, playerId string) ([]ChatMessage, error) {
+	history, err := c.repo.GetHistory(roomId)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]ChatMessage, 0, len(history))
+	for _, message := range history {
+		if message.Channel == ChatChannelTeam && !slices.Contains(message.Recipients, playerId) {
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+func (c *ChatService) playerRoom(playerId string) (*Room, error) {
+	roomId, err := c.roomRepo.GetPlayerRoom(playerId)
+	if err != nil {
+		return nil, err
+	}
+	if roomId == nil {
+		return nil, apperrors.NewAppError(404, "User room not found", nil)
+	}
+	return c.roomRepo.GetRoom(roomId.(string))
+}
+
+// playerTeam returns a player of a room and its team, the team is 0 when the player is not in the room
+func playerTeam(room *Room, playerId string) (Player, int) {
+	for i, players := range [][]Player{room.Team1, room.Team2} {
+		for _, player := range players {
+			if player.ID == playerId {
+				return player, i + 1
+			}
+		}
+	}
+	return Player{}, 0
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/pkg/db"
+)
+
+// chatHistorySize is how many messages of a room are kept for the players that connect later
+const chatHistorySize = 30
+
+// chatHistoryRetention is how long the history of a room without new messages is kept
+const chatHistoryRetention = time.Hour
+
+type ChatRepository interface {
+	AppendMessage(roomId string, message ChatMessage) error
+	GetHistory(roomId string) ([]ChatMessage, error)
+}
+
+type RedisChatRepository struct {
+	db *redis.Client
+}
+
+func NewRedisChatRepository(db *redis.Client) *RedisChatRepository {
+	return &RedisChatRepository{db: db}
+}
+
+// AppendMessage adds a message to the history of a room, dropping the oldest ones
+func (r *RedisChatRepository) AppendMessage(roomId string, message ChatMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return apperrors.NewAppError(500, "Error serializing chat message", err)
+	}
+
+	key := db.ChatKey(roomId)
+	pipe := r.db.TxPipeline()
+	pipe.RPush(ctx, key, data)
+	pipe.LTrim(ctx, key, -chatHistorySize, -1)
+	pipe.Expire(ctx, key, chatHistoryRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return apperrors.NewAppError(500, "Error saving chat message", err)
+	}
+	return nil
+}
+
+// GetHistory returns the last messages of a room, oldest first
+func (r *RedisChatRepository) GetHistory(roomId string) ([]ChatMessage, error) {
+	values, err := r.db.LRange(ctx, db.ChatKey(roomId), 0, -1).Result()
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error getting chat history", err)
+	}
+
+	messages := make([]ChatMessage, 0, len(values))
+	for _, value := range values {
+		var message ChatMessage
+		if err := json.Unmarshal([]byte(value), &message); err != nil {
+			log.Println("Error decoding chat message:", err)
+			continue
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
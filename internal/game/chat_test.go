@@ -0,0 +1,140 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatServiceSendMessageValidatesText(t *testing.T) {
+	_, repo, _ := newLobby(t)
+	chat := NewChatService(NewMemoryChatRepository(), repo)
+
+	_, err := chat.SendMessage("1", ChatChannelAll, "   ")
+	assert.EqualError(t, err, "Chat message is empty")
+	_, err = chat.SendMessage("1", ChatChannelAll, strings.Repeat("a", maxChatLength+1))
+	assert.EqualError(t, err, "Chat message is too long")
+	_, err = chat.SendMessage("1", "whisper", "hi")
+	assert.EqualError(t, err, "Unknown chat channel whisper")
+	_, err = chat.SendMessage("9", ChatChannelAll, "hi")
+	assert.EqualError(t, err, "User room not found")
+}
+
+func TestChatServiceSendMessageAppliesFilter(t *testing.T) {
+	_, repo, room := newLobby(t)
+	chat := NewChatService(NewMemoryChatRepository(), repo)
+	chat.SetFilter(func(text string) string { return strings.ReplaceAll(text, "noob", "****") })
+
+	message, err := chat.SendMessage("1", "", "  gg noob ")
+	assert.NoError(t, err)
+	assert.Equal(t, "gg ****", message.Text)
+	assert.Equal(t, ChatChannelAll, message.Channel)
+	assert.Equal(t, "player", message.Username)
+
+	history, err := chat.History(room.ID, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, []ChatMessage{*message}, history)
+}
+
+func TestChatServiceHistoryHidesOtherTeamMessages(t *testing.T) {
+	_, repo, room := newLobby(t)
+	chat := NewChatService(NewMemoryChatRepository(), repo)
+	_, team := playerTeam(room, "1")
+	var teammate, opponent string
+	for _, id := range []string{"2", "3"} {
+		if _, other := playerTeam(room, id); other == team {
+			teammate = id
+		} else {
+			opponent = id
+		}
+	}
+
+	_, err := chat.SendMessage("1", ChatChannelTeam, "push left")
+	assert.NoError(t, err)
+	_, err = chat.SendMessage("1", ChatChannelAll, "glhf")
+	assert.NoError(t, err)
+
+	history, _ := chat.History(room.ID, teammate)
+	assert.Len(t, history, 2)
+	history, _ = chat.History(room.ID, opponent)
+	assert.Len(t, history, 1)
+	assert.Equal(t, "glhf", history[0].Text)
+}
+
+func TestChatServiceHistoryKeepsTeamAtSendTime(t *testing.T) {
+	rs, repo, room := newLobby(t)
+	chat := NewChatService(NewMemoryChatRepository(), repo)
+	// Team1: 1, 3 and Team2: 2
+	assert.Len(t, room.Team1, 2)
+
+	_, err := chat.SendMessage("1", ChatChannelTeam, "push left")
+	assert.NoError(t, err)
+	_, err = rs.SwitchTeam("3")
+	assert.NoError(t, err)
+	_, err = rs.SwitchTeam("2")
+	assert.NoError(t, err)
+
+	history, _ := chat.History(room.ID, "2")
+	assert.Empty(t, history)
+	history, _ = chat.History(room.ID, "3")
+	assert.Len(t, history, 1)
+}
+
+func TestChatServiceTeamMessageOnlyReachesSenderTeam(t *testing.T) {
+	_, repo, room := newLobby(t)
+	chat := NewChatService(NewMemoryChatRepository(), repo)
+	published := make(chan GameMessage, 1)
+	repo.bus.Subscribe(roomChannel(room.ID), func(payload string) {
+		var message GameMessage
+		if json.Unmarshal([]byte(payload), &message) == nil && message.Type == "CHAT" {
+			published <- message
+		}
+	})
+
+	_, err := chat.SendMessage("1", ChatChannelTeam, "push left")
+	assert.NoError(t, err)
+
+	var message GameMessage
+	select {
+	case message = <-published:
+	case <-time.After(time.Second):
+		t.Fatal("the chat message was not published")
+	}
+	_, team := playerTeam(room, "1")
+	expected := []string{}
+	for _, player := range [][]Player{room.Team1, room.Team2}[team-1] {
+		expected = append(expected, player.ID)
+	}
+	assert.ElementsMatch(t, expected, message.Users)
+	assert.Contains(t, message.Users, "1")
+	assert.Less(t, len(message.Users), room.Players)
+}
+
+func TestMemoryRoomRepositoryDeleteRoomClearsChatHistory(t *testing.T) {
+	_, repo, room := newLobby(t)
+	chatRepo := NewMemoryChatRepository()
+	repo.SetChatRepository(chatRepo)
+	chat := NewChatService(chatRepo, repo)
+	_, err := chat.SendMessage("1", ChatChannelAll, "glhf")
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.DeleteRoom(room.ID))
+	history, err := chatRepo.GetHistory(room.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+}
+
+func TestMemoryChatRepositoryKeepsLastMessages(t *testing.T) {
+	repo := NewMemoryChatRepository()
+	for i := 0; i < chatHistorySize+5; i++ {
+		assert.NoError(t, repo.AppendMessage("room", ChatMessage{Text: strings.Repeat("a", i+1)}))
+	}
+
+	history, err := repo.GetHistory("room")
+	assert.NoError(t, err)
+	assert.Len(t, history, chatHistorySize)
+	assert.Len(t, history[0].Text, 6)
+}
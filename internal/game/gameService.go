@@ -2,25 +2,38 @@ package game
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
 	"math/rand"
+	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game/maps"
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
 	"github.com/thesrcielos/TopTankBattle/internal/user"
+	"github.com/thesrcielos/TopTankBattle/websocket/transport"
 )
 
 const tileSize = 32
+const respawnDelay = 6 * time.Second
+
+// TickRate is how many times per second the game loop runs
+const TickRate = 40
+
+// roomCheckTicks is how often, in ticks, the leader checks that the room of its game still exists
+const roomCheckTicks = TickRate
 const MAP_HEIGHT = 832
 const MAP_WIDTH = 1984
 
 type LeaderElector interface {
 	AttemptLeadership(roomId string)
+	ResyncRoom(roomId string)
 }
 
 // GameService interface that defines the services that offers game
@@ -32,6 +45,9 @@ type GameService interface {
 	MovePlayer(playerId string, newPosition state.Position)
 	SendGameChangeMessage(roomId string, msg GameMessage)
 	ShootBullet(bullet *state.Bullet)
+	ReportLatency(playerId string, rtt time.Duration)
+	Snapshot(roomId string) (*Room, *state.GameState, error)
+	NotifyPlayerConnection(playerId string, connected bool)
 	getPlayerIdsFromRoomAndTeam(roomId string, playerId string) ([]string, bool)
 	getPlayerIdsFromRoom(roomId string, playerId string) []string
 	RunGameLoop(state *state.GameState, test bool)
@@ -53,6 +69,20 @@ type GameServiceImpl struct {
 	roomRepo    RoomRepository
 	userService *user.UserService
 	repo        GameStateRepository
+	registry    InstanceRegistry
+
+	games        map[string]*runningGame
+	following    map[string]bool
+	gamesMu      sync.Mutex
+	shuttingDown atomic.Bool
+	draining     atomic.Bool
+}
+
+// runningGame is a game loop led by this instance
+type runningGame struct {
+	state *state.GameState
+	stop  chan struct{}
+	done  chan struct{}
 }
 
 // NewGameService Constructor for Creating a GameServieImpl
@@ -61,11 +91,17 @@ func NewGameService(repo GameStateRepository, roomRepo RoomRepository, roomServi
 		roomRepo:    roomRepo,
 		roomService: roomService,
 		userService: userService,
+		games:       make(map[string]*runningGame),
+		following:   make(map[string]bool),
 	}
 }
 
 // StartGame Set up all the configurations neeeded to start a game
 func (s *GameServiceImpl) StartGame(playerId string, roomId string, test bool) error {
+	if s.draining.Load() {
+		return apperrors.NewAppError(503, "Cannot start game: instance is draining, try again", nil)
+	}
+
 	room, err := s.roomRepo.GetRoom(roomId)
 	if err != nil {
 		fmt.Println("Error Obtainig room", err)
@@ -144,36 +180,81 @@ func (s *GameServiceImpl) StartGame(playerId string, roomId string, test bool) e
 		return err
 	}
 
+	token, ok := s.repo.TryToBecomeLeader(roomId)
+	if !ok {
+		s.detachGameState(gameState)
+		return apperrors.NewAppError(409, "Cannot start game: room is led by another instance", nil)
+	}
+	gameState.Fence = token
+
+	_, err = retryOnConflict(func() (*Room, error) {
+		current, err := s.roomRepo.GetRoom(roomId)
+		if err != nil {
+			return nil, err
+		}
+		if current.Status != "LOBBY" || !sameTeams(current, room) {
+			return nil, apperrors.NewAppError(409, "Cannot start game: the room changed, try again", nil)
+		}
+		current.Status = "PLAYING"
+		return current, s.roomRepo.SaveRoom(current)
+	})
+	if err != nil {
+		s.detachGameState(gameState)
+		s.repo.ReleaseLeadership(roomId, token)
+		return err
+	}
 	s.NotifyGameStart(gameState)
-	room.Status = "PLAYING"
-	s.roomRepo.SaveRoom(room)
-	s.repo.SaveGameState(gameState)
-	go s.RunGameLoop(gameState, test)
-	s.repo.TryToBecomeLeader(roomId)
-	msg := GameMessage{
+	if err := s.repo.SaveGameState(gameState); err != nil {
+		log.Println("Error saving game state:", err)
+	}
+	go func() {
+		s.RunGameLoop(gameState, test)
+		if !test {
+			s.AttemptLeadership(roomId)
+		}
+	}()
+	s.sendControlMessage(gameState, GameMessage{
 		Type: "GAME_START_INFO",
-		Payload: map[string]string{
-			"roomId":   roomId,
-			"instance": instanceID,
+		Payload: GameInfo{
+			Instance: instanceID,
+			RoomId:   roomId,
 		},
-	}
-	s.SendGameChangeMessage(roomId, msg)
+	})
 	return nil
 }
 
+// sameTeams reports if two versions of a room have the same players in the same teams
+func sameTeams(a *Room, b *Room) bool {
+	if len(a.Team1) != len(b.Team1) || len(a.Team2) != len(b.Team2) {
+		return false
+	}
+	for i, player := range a.Team1 {
+		if b.Team1[i].ID != player.ID {
+			return false
+		}
+	}
+	for i, player := range a.Team2 {
+		if b.Team2[i].ID != player.ID {
+			return false
+		}
+	}
+	return true
+}
+
 // NotifyGameStart sNotifies to players the starting game by redis pub sub
 func (s *GameServiceImpl) NotifyGameStart(game *state.GameState) {
 	message := GameMessage{
 		Type:    "GAME_START",
 		Payload: game,
 		Users:   s.getGamePlayerIds(game, ""),
+		Room:    game.RoomId,
 	}
 	msg, err := json.Marshal(message)
 	if err != nil {
 		log.Println("Error encoding message:", err)
 		return
 	}
-	s.repo.PublishToRoom(string(msg))
+	s.repo.PublishToRoom(game.RoomId, string(msg))
 }
 
 // SetPlayersGameState sets gameState to the PlayerConnections
@@ -202,26 +283,288 @@ func (s *GameServiceImpl) SetPlayersGameState(gameState *state.GameState) error
 
 // AttemptLeadership tries to be the leader of the game if an instance fails
 func (s *GameServiceImpl) AttemptLeadership(roomId string) {
+	if !s.follow(roomId) {
+		return
+	}
+	defer s.unfollow(roomId)
+
 	ticker := time.NewTicker(1000 * time.Millisecond)
+	defer ticker.Stop()
 
-	for range ticker.C {
-		room, err := s.roomRepo.GetRoom(roomId)
-		if err != nil {
-			continue
+	for !s.leadRoomIfPlaying(roomId) {
+		<-ticker.C
+	}
+}
+
+// follow registers that this instance is watching the leader of a room, it fails if it already is
+func (s *GameServiceImpl) follow(roomId string) bool {
+	s.gamesMu.Lock()
+	defer s.gamesMu.Unlock()
+
+	if s.following[roomId] {
+		return false
+	}
+	s.following[roomId] = true
+	return true
+}
+
+func (s *GameServiceImpl) unfollow(roomId string) {
+	s.gamesMu.Lock()
+	delete(s.following, roomId)
+	s.gamesMu.Unlock()
+}
+
+// ResyncRoom refreshes a room after this instance missed some of its events: the local players
+// receive the current room and, while the game is running, the leader is watched again
+func (s *GameServiceImpl) ResyncRoom(roomId string) {
+	room, err := s.roomRepo.GetRoom(roomId)
+	if err != nil {
+		log.Println("Error resyncing room:", err)
+		return
+	}
+
+	msg := transport.OutgoingMessage{
+		Type:    "ROOM_INFO",
+		Payload: room,
+	}
+	for _, team := range [][]Player{room.Team1, room.Team2} {
+		for _, player := range team {
+			if state.GetPlayer(player.ID) != nil {
+				transport.SendToPlayer(player.ID, msg)
+			}
 		}
-		if room.Status != "PLAYING" {
-			ticker.Stop()
-			return
+	}
+
+	if room.Status == "PLAYING" && !s.isLeading(roomId) {
+		go s.AttemptLeadership(roomId)
+	}
+}
+
+// leadRoomIfPlaying tries once to take the leadership of a room and runs its game loop while leading.
+// It returns true when there is nothing left to lead, a draining instance keeps watching the room.
+func (s *GameServiceImpl) leadRoomIfPlaying(roomId string) bool {
+	if s.shuttingDown.Load() {
+		return true
+	}
+
+	room, err := s.roomRepo.GetRoom(roomId)
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) && appErr.Code == 404 {
+		return true
+	} else if err != nil {
+		return false
+	}
+	if room.Status != "PLAYING" {
+		return true
+	}
+
+	if s.draining.Load() || s.isLeading(roomId) {
+		return false
+	}
+
+	token, ok := s.repo.TryToBecomeLeader(roomId)
+	if !ok {
+		return false
+	}
+	fmt.Printf("[INFO] Instance %s is now leader of the room %s with token %d\n", instanceID, roomId, token)
+
+	// Recuperar estado anterior desde Redis
+	gameState, err := s.repo.RestoreGameState(roomId)
+	if err != nil {
+		log.Println("Error restoring game state:", err)
+		return false
+	}
+	gameState.Fence = token
+	s.attachGameState(gameState)
+
+	s.RunGameLoop(gameState, false)
+	return false
+}
+
+// isLeading reports if this instance is running the game loop of a room
+func (s *GameServiceImpl) isLeading(roomId string) bool {
+	s.gamesMu.Lock()
+	defer s.gamesMu.Unlock()
+
+	_, ok := s.games[roomId]
+	return ok
+}
+
+// LedRooms returns the rooms whose game loop runs on this instance
+func (s *GameServiceImpl) LedRooms() []string {
+	s.gamesMu.Lock()
+	defer s.gamesMu.Unlock()
+
+	rooms := make([]string, 0, len(s.games))
+	for roomId := range s.games {
+		rooms = append(rooms, roomId)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// trackGame registers a game loop led by this instance, it fails if the room already has one
+func (s *GameServiceImpl) trackGame(gameState *state.GameState) (*runningGame, bool) {
+	s.gamesMu.Lock()
+	defer s.gamesMu.Unlock()
+
+	if _, ok := s.games[gameState.RoomId]; ok {
+		return nil, false
+	}
+	game := &runningGame{
+		state: gameState,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	s.games[gameState.RoomId] = game
+	return game, true
+}
+
+// untrackGame removes a finished game loop
+func (s *GameServiceImpl) untrackGame(game *runningGame) {
+	s.gamesMu.Lock()
+	delete(s.games, game.state.RoomId)
+	s.gamesMu.Unlock()
+	close(game.done)
+}
+
+// Shutdown stops leading games on this instance and hands them off to the peer instances
+func (s *GameServiceImpl) Shutdown() {
+	s.shuttingDown.Store(true)
+	s.HandOffGames()
+}
+
+// SetInstanceRegistry sets the registry used to find the peers that can take over games
+func (s *GameServiceImpl) SetInstanceRegistry(registry InstanceRegistry) {
+	s.registry = registry
+}
+
+// Drain stops this instance from starting or taking over games before a deploy. Running games
+// are handed off to the peer instances right away or left to finish when no peer can take them.
+// It returns true when the games were handed off.
+func (s *GameServiceImpl) Drain(handOff bool) bool {
+	s.draining.Store(true)
+	if handOff && !s.hasLivePeer() {
+		log.Printf("Instance %s has no live peer to hand off games to, letting them finish", instanceID)
+		handOff = false
+	}
+	log.Printf("Instance %s draining, handing off games: %t", instanceID, handOff)
+	if handOff {
+		s.HandOffGames()
+	}
+	return handOff
+}
+
+// hasLivePeer reports if another live instance that is not draining can take over games
+func (s *GameServiceImpl) hasLivePeer() bool {
+	if s.registry == nil {
+		return false
+	}
+	instances, err := s.registry.ListInstances()
+	if err != nil {
+		log.Println("Error listing instances:", err)
+		return false
+	}
+	for _, instance := range instances {
+		if instance.ID != instanceID && instance.Alive && !instance.Draining {
+			return true
 		}
+	}
+	return false
+}
+
+// Resume takes the instance out of drain mode
+func (s *GameServiceImpl) Resume() {
+	s.draining.Store(false)
+	log.Printf("Instance %s resumed", instanceID)
+}
 
-		if s.repo.TryToBecomeLeader(roomId) {
-			fmt.Printf("[INFO] Instance %s is now leader of the room %s\n", instanceID, roomId)
+// IsDraining reports if the instance is in drain mode
+func (s *GameServiceImpl) IsDraining() bool {
+	return s.draining.Load()
+}
 
-			// Recuperar estado anterior desde Redis
-			state := s.repo.RestoreGameState(roomId)
+// HandOffGames stops every game loop led by this instance, checkpoints its state and releases
+// the leadership so a peer instance can take over immediately
+func (s *GameServiceImpl) HandOffGames() {
+	s.gamesMu.Lock()
+	games := make([]*runningGame, 0, len(s.games))
+	for _, game := range s.games {
+		games = append(games, game)
+	}
+	s.gamesMu.Unlock()
 
-			s.RunGameLoop(state, false)
+	for _, game := range games {
+		close(game.stop)
+		<-game.done
+		s.handOffGame(game.state)
+	}
+}
+
+// handOffGame saves the last checkpoint of a stopped game, releases its leader key and tells the peers
+func (s *GameServiceImpl) handOffGame(gameState *state.GameState) {
+	gameState.GameMu.Lock()
+	err := s.repo.SaveGameState(gameState)
+	gameState.GameMu.Unlock()
+	if err != nil {
+		log.Println("Error saving game state before handoff:", err)
+		return
+	}
+
+	if err := s.repo.ReleaseLeadership(gameState.RoomId, gameState.Fence); err != nil {
+		log.Println("Error releasing leadership:", err)
+	}
+	s.detachGameState(gameState)
+
+	s.sendControlMessage(gameState, GameMessage{
+		Type: "GAME_HANDOFF",
+		Payload: GameInfo{
+			Instance: instanceID,
+			RoomId:   gameState.RoomId,
+		},
+	})
+	log.Printf("Room %s handed off by instance %s", gameState.RoomId, instanceID)
+}
+
+// attachGameState points the local player connections to a restored game state
+func (s *GameServiceImpl) attachGameState(gameState *state.GameState) {
+	for playerId := range gameState.Players {
+		player := state.GetPlayer(playerId)
+		if player == nil {
+			continue
+		}
+		player.ConnMu.Lock()
+		player.GameState = gameState
+		player.ConnMu.Unlock()
+	}
+}
+
+// detachGameState makes the local player connections forward their actions to the room leader
+func (s *GameServiceImpl) detachGameState(gameState *state.GameState) {
+	for playerId := range gameState.Players {
+		player := state.GetPlayer(playerId)
+		if player == nil {
+			continue
 		}
+		player.ConnMu.Lock()
+		if player.GameState == gameState {
+			player.GameState = nil
+		}
+		player.ConnMu.Unlock()
+	}
+}
+
+// RoomActivated subscribes to the events of a room when its first player connects to this instance
+func (s *GameServiceImpl) RoomActivated(roomId string) {
+	if err := s.repo.SubscribeRoom(roomId); err != nil {
+		log.Println("Error subscribing to room:", err)
+	}
+}
+
+// RoomDeactivated unsubscribes from a room when its last local player is gone
+func (s *GameServiceImpl) RoomDeactivated(roomId string) {
+	if err := s.repo.UnsubscribeRoom(roomId); err != nil {
+		log.Println("Error unsubscribing from room:", err)
 	}
 }
 
@@ -251,6 +594,12 @@ func (s *GameServiceImpl) ValidateRoom(room *Room, playerId string) error {
 		return apperrors.NewAppError(400, "Cannot start game: teams must have at most 1 player more than the other team", nil)
 	}
 
+	for _, player := range append(append([]Player{}, room.Team1...), room.Team2...) {
+		if player.ID != room.Host.ID && !room.IsReady(player.ID) {
+			return apperrors.NewAppError(400, "Cannot start game: not all players are ready", nil)
+		}
+	}
+
 	return nil
 }
 
@@ -312,7 +661,26 @@ func (s *GameServiceImpl) SendGameChangeMessage(roomId string, msg GameMessage)
 		return
 	}
 
-	s.repo.PublishToRoom(string(message))
+	s.repo.PublishToRoom(roomId, string(message))
+}
+
+// sendLeaderMessage stamps a game event with the leader fencing token so followers can discard stale leaders
+func (s *GameServiceImpl) sendLeaderMessage(game *state.GameState, msg GameMessage) {
+	msg.Room = game.RoomId
+	msg.Fence = game.Fence
+	s.SendGameChangeMessage(game.RoomId, msg)
+}
+
+// sendControlMessage sends a leadership event of a game to every instance
+func (s *GameServiceImpl) sendControlMessage(game *state.GameState, msg GameMessage) {
+	msg.Room = game.RoomId
+	msg.Fence = game.Fence
+	message, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("Error encoding message:", err)
+		return
+	}
+	s.repo.PublishControl(string(message))
 }
 
 func (s *GameServiceImpl) ShootBullet(bullet *state.Bullet) {
@@ -327,6 +695,7 @@ func (s *GameServiceImpl) ShootBullet(bullet *state.Bullet) {
 
 	if player.GameState == nil {
 		players, team1 := s.getPlayerIdsFromRoomAndTeam(player.RoomId, bullet.OwnerId)
+		bullet.Team1 = team1
 		msg.Payload = ShootMessage{
 			ID:       bullet.ID,
 			Position: bullet.Position,
@@ -352,23 +721,106 @@ func (s *GameServiceImpl) ShootBullet(bullet *state.Bullet) {
 		playerState.PlayerMu.Unlock()
 		return
 	}
-	team1 := playerState.Team1
+	bullet.Team1 = playerState.Team1
+	msg.Users = s.getGamePlayerIds(game, bullet.OwnerId)
+	playerState.PlayerMu.Unlock()
+
+	game.GameMu.Lock()
+	game.AddBullet(bullet)
 	msg.Payload = ShootMessage{
 		ID:       bullet.ID,
 		Position: bullet.Position,
-		Team1:    team1,
+		Team1:    bullet.Team1,
 		OwnerId:  bullet.OwnerId,
 	}
-
-	msg.Users = s.getGamePlayerIds(game, bullet.OwnerId)
-	playerState.PlayerMu.Unlock()
-
-	game.GameMu.Lock()
-	game.Bullets[bullet.ID] = bullet
 	game.GameMu.Unlock()
 	s.SendGameChangeMessage(game.RoomId, msg)
 }
 
+// Snapshot returns a room and, when it is being played, the last checkpoint of its game
+func (s *GameServiceImpl) Snapshot(roomId string) (*Room, *state.GameState, error) {
+	room, err := s.roomRepo.GetRoom(roomId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if room.Status != "PLAYING" {
+		return room, nil, nil
+	}
+
+	game, err := s.repo.RestoreGameState(roomId)
+	if err != nil {
+		log.Println("Error restoring game snapshot:", err)
+		return room, nil, nil
+	}
+	return room, game, nil
+}
+
+// NotifyPlayerConnection tells the teammates of a player that it lost or recovered its connection
+func (s *GameServiceImpl) NotifyPlayerConnection(playerId string, connected bool) {
+	player := state.GetPlayer(playerId)
+	if player == nil {
+		return
+	}
+	room, err := s.roomRepo.GetRoom(player.RoomId)
+	if err != nil {
+		log.Println("Error getting room of player", playerId, ":", err)
+		return
+	}
+
+	msgType := "PLAYER_DISCONNECTED"
+	if connected {
+		msgType = "PLAYER_RECONNECTED"
+	}
+	s.SendGameChangeMessage(room.ID, GameMessage{
+		Type:    msgType,
+		Payload: PlayerConnectionMessage{PlayerId: playerId},
+		Users:   teammates(room, playerId),
+	})
+}
+
+// teammates returns the ids of the other players in the team of a player
+func teammates(room *Room, playerId string) []string {
+	for _, team := range [][]Player{room.Team1, room.Team2} {
+		ids := []string{}
+		found := false
+		for _, player := range team {
+			if player.ID == playerId {
+				found = true
+				continue
+			}
+			ids = append(ids, player.ID)
+		}
+		if found {
+			return ids
+		}
+	}
+	return nil
+}
+
+// ReportLatency shares the round trip time of a player with its room, the clients show it
+// on the scoreboard and the leader uses it to compensate the lag of the player shots
+func (s *GameServiceImpl) ReportLatency(playerId string, rtt time.Duration) {
+	player := state.GetPlayer(playerId)
+	if player == nil {
+		return
+	}
+
+	var users []string
+	if player.GameState != nil {
+		users = s.getGamePlayerIds(player.GameState, "")
+	} else {
+		users = s.getPlayerIdsFromRoom(player.RoomId, "")
+	}
+	s.SendGameChangeMessage(player.RoomId, GameMessage{
+		Type: "LATENCY",
+		Payload: LatencyMessage{
+			PlayerId: playerId,
+			Latency:  rtt.Milliseconds(),
+		},
+		Users: users,
+	})
+}
+
 // getPlayerIdsFromRoomAndTeam gets the players ids from the room and get the team of the player
 func (s *GameServiceImpl) getPlayerIdsFromRoomAndTeam(roomId string, playerId string) ([]string, bool) {
 	room, err := s.roomRepo.GetRoom(roomId)
@@ -425,19 +877,38 @@ func (s *GameServiceImpl) RunGameLoop(state *state.GameState, test bool) {
 	if test {
 		return
 	}
+	game, ok := s.trackGame(state)
+	if !ok {
+		return
+	}
+	defer s.untrackGame(game)
+
+	// The leader must hear the actions of players connected to other instances
+	if err := s.repo.SubscribeRoom(state.RoomId); err != nil {
+		log.Println("Error subscribing to room:", err)
+	}
+	defer s.repo.UnsubscribeRoom(state.RoomId)
+
 	users := s.getGamePlayerIds(state, "")
-	ticker := time.NewTicker(25 * time.Millisecond) // ~40 FPS
+	ticker := time.NewTicker(time.Second / TickRate)
 	defer ticker.Stop()
 	gameOver := false
 
 	const fixeDelta = 0.025 // Fixed delta time for physics updates
-	for range ticker.C {
-		if gameOver {
-			break
+	for !gameOver {
+		select {
+		case <-game.stop:
+			return
+		case <-ticker.C:
 		}
 
 		state.GameMu.Lock()
 		const bulletDamage = 20
+		state.Tick++
+
+		for _, playerId := range state.DueRespawns(time.Now()) {
+			s.RevivePlayer(playerId, state)
+		}
 
 		s.UpdateBullets(state.Bullets, fixeDelta)
 
@@ -461,26 +932,64 @@ func (s *GameServiceImpl) RunGameLoop(state *state.GameState, test bool) {
 				continue
 			}
 		}
-		s.repo.SaveGameState(state)
+		if gameOver {
+			// FinishGame already cleaned up the room keys
+			state.GameMu.Unlock()
+			return
+		}
+		err := s.repo.SaveGameState(state)
+		checkRoom := state.Tick%roomCheckTicks == 0
 		state.GameMu.Unlock()
+		if errors.Is(err, ErrStaleLeader) {
+			log.Println("Stopping game loop of stale leader for room", state.RoomId)
+			return
+		}
+		if checkRoom && !s.roomIsPlaying(state.RoomId) {
+			log.Println("Stopping game loop of removed room", state.RoomId)
+			s.abandonGame(state)
+			return
+		}
 
-		renew, err := s.repo.RenewLeadership(state.RoomId, 5000*time.Millisecond)
+		renew, err := s.repo.RenewLeadership(state.RoomId, state.Fence, leaderExpiration)
 		if err != nil {
 			continue
 		}
 
 		if !renew {
-			s.AttemptLeadership(state.RoomId)
 			return
 		}
 	}
 }
 
+// roomIsPlaying reports if the room of a game still exists and is playing, errors other than a
+// missing room keep the game running
+func (s *GameServiceImpl) roomIsPlaying(roomId string) bool {
+	room, err := s.roomRepo.GetRoom(roomId)
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) && appErr.Code == 404 {
+		return false
+	} else if err != nil {
+		return true
+	}
+	return room.Status == "PLAYING"
+}
+
+// abandonGame drops a game whose room is gone: its state is deleted and the leadership released
+func (s *GameServiceImpl) abandonGame(gameState *state.GameState) {
+	s.detachGameState(gameState)
+	if err := s.repo.DeleteGameState(gameState.RoomId, gameState.Fence); err != nil {
+		log.Println("Error deleting game state:", err)
+	}
+	if err := s.repo.ReleaseLeadership(gameState.RoomId, gameState.Fence); err != nil {
+		log.Println("Error releasing leadership:", err)
+	}
+}
+
 // HandleHitFortress handles collision with a hit fortress
 func (s *GameServiceImpl) HandleHitFortress(hitFortress *state.Fortress, state *state.GameState, bulletDamage int, bulletId string, users []string) bool {
 	hitFortress.Health -= bulletDamage
 	if hitFortress.Health <= 0 {
-		s.SendGameChangeMessage(state.RoomId, GameMessage{
+		s.sendLeaderMessage(state, GameMessage{
 			Type: "GAME_OVER",
 			Payload: map[string]interface{}{
 				"team1": !hitFortress.Team1,
@@ -490,7 +999,7 @@ func (s *GameServiceImpl) HandleHitFortress(hitFortress *state.Fortress, state *
 		s.FinishGame(state)
 		return true
 	} else {
-		s.SendGameChangeMessage(state.RoomId, GameMessage{
+		s.sendLeaderMessage(state, GameMessage{
 			Type: "FORTRESS_HIT",
 			Payload: map[string]interface{}{
 				"team1":  hitFortress.Team1,
@@ -508,7 +1017,7 @@ func (s *GameServiceImpl) HandleHitPlayer(hitPlayer *state.PlayerState, state *s
 	hitPlayer.Health -= bulletDamage
 	delete(state.Bullets, bulletId)
 	if hitPlayer.Health > 0 {
-		s.SendGameChangeMessage(state.RoomId, GameMessage{
+		s.sendLeaderMessage(state, GameMessage{
 			Type: "PLAYER_HIT",
 			Payload: map[string]interface{}{
 				"playerId": hitPlayer.ID,
@@ -517,14 +1026,14 @@ func (s *GameServiceImpl) HandleHitPlayer(hitPlayer *state.PlayerState, state *s
 			Users: users,
 		})
 	} else {
-		s.SendGameChangeMessage(state.RoomId, GameMessage{
+		s.sendLeaderMessage(state, GameMessage{
 			Type: "PLAYER_KILLED",
 			Payload: map[string]interface{}{
 				"playerId": hitPlayer.ID,
 			},
 			Users: users,
 		})
-		go s.RevivePlayer(hitPlayer.ID, state)
+		state.ScheduleRespawn(hitPlayer.ID, time.Now().Add(respawnDelay))
 	}
 }
 
@@ -552,7 +1061,10 @@ func (s *GameServiceImpl) CheckBulletCollision(bullet *state.Bullet, players map
 		{rightX, rightY},
 	}
 
-	team1 := players[bullet.OwnerId].Team1
+	team1 := bullet.Team1
+	if owner, ok := players[bullet.OwnerId]; ok {
+		team1 = owner.Team1
+	}
 	obstacles := maps.Matrix
 
 	// 1. Check Obstacle collision para cada punto
@@ -669,10 +1181,12 @@ func (s *GameServiceImpl) UpdateBullets(bullets map[string]*state.Bullet, delta
 	}
 }
 
-// RevivePlayer handles the logic to revive a dead player
+// RevivePlayer handles the logic to revive a dead player once its respawn time is due
 func (s *GameServiceImpl) RevivePlayer(playerId string, gameState *state.GameState) {
-	time.Sleep(6 * time.Second)
-	player := gameState.Players[playerId]
+	player, ok := gameState.Players[playerId]
+	if !ok {
+		return
+	}
 	player.PlayerMu.Lock()
 	player.Health = 100
 	seed := time.Now().UnixNano()
@@ -691,7 +1205,7 @@ func (s *GameServiceImpl) RevivePlayer(playerId string, gameState *state.GameSta
 	}
 	player.Position = pos
 	player.PlayerMu.Unlock()
-	s.SendGameChangeMessage(gameState.RoomId, GameMessage{
+	s.sendLeaderMessage(gameState, GameMessage{
 		Type: "PLAYER_REVIVED",
 		Payload: map[string]interface{}{
 			"playerId": playerId,
@@ -719,16 +1233,21 @@ func (s *GameServiceImpl) FinishGame(game *state.GameState) {
 		player.GameState = nil
 		player.ConnMu.Unlock()
 	}
-	room, err := s.roomRepo.GetRoom(game.RoomId)
+	room, err := retryOnConflict(func() (*Room, error) {
+		room, err := s.roomRepo.GetRoom(game.RoomId)
+		if err != nil {
+			return nil, err
+		}
+		room.Status = "LOBBY"
+		room.Ready = nil
+		return room, s.roomRepo.SaveRoom(room)
+	})
 	if err != nil {
-		log.Println("error getting ", err)
+		log.Println("error saving ", err)
 		return
 	}
-	room.Status = "LOBBY"
-	errDB := s.roomRepo.SaveRoom(room)
-	if errDB != nil {
-		log.Println("error saving ", errDB)
-		return
+	if err := s.repo.DeleteGameState(game.RoomId, game.Fence); err != nil {
+		log.Println("Error deleting game state:", err)
 	}
 
 	msg := GameMessage{
@@ -1,8 +1,11 @@
 package game
 
 import (
+	"encoding/json"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -41,6 +44,7 @@ func TestValidateRoomOk(t *testing.T) {
 		Status: "LOBBY",
 		Team1:  []Player{{ID: "p1"}},
 		Team2:  []Player{{ID: "p2"}},
+		Ready:  []string{"p1", "p2"},
 	}
 	err := gameService.ValidateRoom(room, "host")
 	require.NoError(t, err)
@@ -204,18 +208,20 @@ func TestStartGameSuccess(t *testing.T) {
 		Host:   Player{ID: playerID},
 		Team1:  []Player{{ID: playerID}},
 		Team2:  []Player{{ID: "player2"}},
+		Ready:  []string{"player2"},
 	}
 
 	// Mock de GetRoom
 	mockRoomRepo.On("GetRoom", roomID).Return(room, nil)
 	mockRoomRepo.On("SaveRoom", mock.Anything).Return(nil)
-	mockGameRepo.On("SaveGameState", mock.Anything).Return()
-	mockGameRepo.On("PublishToRoom", mock.Anything).Return()
-	mockGameRepo.On("TryToBecomeLeader", roomID).Return(true)
+	mockGameRepo.On("SaveGameState", mock.Anything).Return(nil)
+	mockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
+	mockGameRepo.On("PublishControl", mock.Anything).Return()
+	mockGameRepo.On("TryToBecomeLeader", roomID).Return(int64(1), true)
 
 	// Simulación básica del estado global del jugador
-	state.RegisterPlayer(playerID, roomID, nil)
-	state.RegisterPlayer("player2", roomID, nil)
+	state.RegisterPlayer(playerID, roomID, nil, "")
+	state.RegisterPlayer("player2", roomID, nil, "")
 
 	err := gameService.StartGame(playerID, roomID, true)
 
@@ -224,7 +230,7 @@ func TestStartGameSuccess(t *testing.T) {
 	mockRoomRepo.AssertCalled(t, "GetRoom", roomID)
 	mockRoomRepo.AssertCalled(t, "SaveRoom", mock.Anything)
 	mockGameRepo.AssertCalled(t, "SaveGameState", mock.Anything)
-	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 	mockGameRepo.AssertCalled(t, "TryToBecomeLeader", roomID)
 }
 
@@ -236,13 +242,14 @@ func TestHandleHitPlayerPlayerHitAndKilled(t *testing.T) {
 		Bullets: map[string]*state.Bullet{"b1": {}},
 	}
 	users := []string{"p2"}
-	mockGameRepo.On("PublishToRoom", mock.Anything).Return()
+	mockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 
 	gameService.HandleHitPlayer(gameState.Players["p1"], gameState, 20, "b1", users)
 	assert.Equal(t, 0, gameState.Players["p1"].Health)
 	_, exists := gameState.Bullets["b1"]
 	assert.False(t, exists)
-	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	assert.Contains(t, gameState.Respawns, "p1")
+	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestHandleHitPlayerPlayerHitButNotKilled(t *testing.T) {
@@ -253,23 +260,24 @@ func TestHandleHitPlayerPlayerHitButNotKilled(t *testing.T) {
 		Bullets: map[string]*state.Bullet{"b1": {}},
 	}
 	users := []string{"p2"}
-	mockGameRepo.On("PublishToRoom", mock.Anything).Return()
+	mockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 	gameService.HandleHitPlayer(gameState.Players["p1"], gameState, 20, "b1", users)
 	assert.Equal(t, 80, gameState.Players["p1"].Health)
 	_, exists := gameState.Bullets["b1"]
 	assert.False(t, exists)
-	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestHandleHitFortressDestroyed(t *testing.T) {
 	fortress := &state.Fortress{ID: "f1", Health: 20, Team1: true}
 	gameState := &state.GameState{RoomId: "room1", Bullets: map[string]*state.Bullet{"b1": {}}, Fortresses: []*state.Fortress{fortress}}
 	users := []string{"p1", "p2"}
-	mockGameRepo.On("PublishToRoom", mock.Anything).Return()
-	mockGameRepo.On("SaveGameState", mock.Anything).Return()
+	mockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
+	mockGameRepo.On("SaveGameState", mock.Anything).Return(nil)
 	mockRoomRepo.On("GetRoom", "room1").Return(&Room{ID: "room1", Host: Player{ID: "host"}}, nil)
 	mockRoomRepo.On("SaveRoom", mock.Anything).Return(nil)
-	mockRoomRepo.On("PublishToRoom", mock.Anything).Return()
+	mockRoomRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
+	mockGameRepo.On("DeleteGameState", "room1", int64(0)).Return(nil)
 	mockUserRepo.On("GetUserUsername", mock.Anything).Return("user", nil)
 	userService = user.NewUserService(mockUserRepo)
 	roomService = NewRoomService(mockRoomRepo)
@@ -277,7 +285,8 @@ func TestHandleHitFortressDestroyed(t *testing.T) {
 	result := gameService.HandleHitFortress(fortress, gameState, 20, "b1", users)
 	assert.True(t, result)
 	assert.Equal(t, 0, fortress.Health)
-	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
+	mockGameRepo.AssertCalled(t, "DeleteGameState", "room1", int64(0))
 }
 
 func TestHandleHitFortressNotDestroyed(t *testing.T) {
@@ -285,13 +294,13 @@ func TestHandleHitFortressNotDestroyed(t *testing.T) {
 	fortress := &state.Fortress{ID: "f1", Health: 100, Team1: true}
 	gameState := &state.GameState{RoomId: "room1", Bullets: map[string]*state.Bullet{"b1": {}}, Fortresses: []*state.Fortress{fortress}}
 	users := []string{"p1", "p2"}
-	mockGameRepo.On("PublishToRoom", mock.Anything).Return()
+	mockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 	result := gameService.HandleHitFortress(fortress, gameState, 20, "b1", users)
 	assert.False(t, result)
 	assert.Equal(t, 80, fortress.Health)
 	_, exists := gameState.Bullets["b1"]
 	assert.False(t, exists)
-	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	mockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestGetPlayerIdsFromRoom(t *testing.T) {
@@ -334,7 +343,7 @@ func TestMovePlayerSendsMessageWhenPlayerExistsAndAlive(t *testing.T) {
 	playerId := "p1"
 	roomId := "room1"
 	pos := state.Position{X: 10, Y: 20, Angle: 0}
-	state.RegisterPlayer(playerId, roomId, nil)
+	state.RegisterPlayer(playerId, roomId, nil, "")
 	playerConn := state.GetPlayer(playerId)
 	playerConn.GameState = nil // Simula que no está en partida
 
@@ -343,10 +352,10 @@ func TestMovePlayerSendsMessageWhenPlayerExistsAndAlive(t *testing.T) {
 		Team1: []Player{{ID: playerId}, {ID: "p2"}},
 		Team2: []Player{{ID: "p3"}},
 	}, nil)
-	localMockGameRepo.On("PublishToRoom", mock.Anything).Return()
+	localMockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 
 	gameService.MovePlayer(playerId, pos)
-	localMockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	localMockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestMovePlayerNoSendWhenPlayerDoesNotExist(t *testing.T) {
@@ -360,7 +369,7 @@ func TestMovePlayerNoSendWhenPlayerDoesNotExist(t *testing.T) {
 	pos := state.Position{X: 10, Y: 20, Angle: 0}
 	// No debe hacer panic ni enviar mensaje
 	gameService.MovePlayer("noexiste", pos)
-	localMockGameRepo.AssertNotCalled(t, "PublishToRoom", mock.Anything)
+	localMockGameRepo.AssertNotCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestShootBulletSendsMessageWithoutGameState(t *testing.T) {
@@ -372,7 +381,7 @@ func TestShootBulletSendsMessageWithoutGameState(t *testing.T) {
 
 	playerId := "p1"
 	roomId := "room1"
-	state.RegisterPlayer(playerId, roomId, nil)
+	state.RegisterPlayer(playerId, roomId, nil, "")
 	playerConn := state.GetPlayer(playerId)
 	playerConn.GameState = nil // Simula que no está en partida
 
@@ -381,11 +390,11 @@ func TestShootBulletSendsMessageWithoutGameState(t *testing.T) {
 		Team1: []Player{{ID: playerId}, {ID: "p2"}},
 		Team2: []Player{{ID: "p3"}},
 	}, nil)
-	localMockGameRepo.On("PublishToRoom", mock.Anything).Return()
+	localMockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 
 	bullet := &state.Bullet{ID: "b1", OwnerId: playerId, Position: state.Position{X: 1, Y: 2, Angle: 0}, Speed: 10}
 	gameService.ShootBullet(bullet)
-	localMockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	localMockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestShootBulletSendsMessageWithGameState(t *testing.T) {
@@ -397,7 +406,7 @@ func TestShootBulletSendsMessageWithGameState(t *testing.T) {
 
 	playerId := "p1"
 	roomId := "room1"
-	state.RegisterPlayer(playerId, roomId, nil)
+	state.RegisterPlayer(playerId, roomId, nil, "")
 	playerConn := state.GetPlayer(playerId)
 	// Simula que está en partida
 	gs := &state.GameState{
@@ -407,11 +416,31 @@ func TestShootBulletSendsMessageWithGameState(t *testing.T) {
 	}
 	playerConn.GameState = gs
 
-	localMockGameRepo.On("PublishToRoom", mock.Anything).Return()
+	localMockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 
 	bullet := &state.Bullet{ID: "b1", OwnerId: playerId, Position: state.Position{X: 1, Y: 2, Angle: 0}, Speed: 10}
 	gameService.ShootBullet(bullet)
-	localMockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	localMockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
+}
+
+func TestShootBulletSendsLagCompensatedPosition(t *testing.T) {
+	localMockGameRepo := new(MockGameStateRepository)
+	localMockRoomRepo := new(MockRoomRepository)
+	gameService := NewGameService(localMockGameRepo, localMockRoomRepo, NewRoomService(localMockRoomRepo), userService)
+
+	state.RegisterPlayer("lagged", "lagRoom", nil, "")
+	defer state.UnregisterPlayer("lagged")
+	state.GetPlayer("lagged").GameState = &state.GameState{
+		RoomId:  "lagRoom",
+		Players: map[string]*state.PlayerState{"lagged": {ID: "lagged", Health: 100, Latency: 100}},
+		Bullets: map[string]*state.Bullet{},
+	}
+	localMockGameRepo.On("PublishToRoom", "lagRoom", mock.Anything).Return()
+
+	gameService.ShootBullet(&state.Bullet{ID: "b1", OwnerId: "lagged", Position: state.Position{X: 1, Y: 2}, Speed: 10})
+	localMockGameRepo.AssertCalled(t, "PublishToRoom", "lagRoom", mock.MatchedBy(func(payload string) bool {
+		return strings.Contains(payload, `"type":"SHOOT"`) && strings.Contains(payload, `"x":1.5`)
+	}))
 }
 
 func TestMovePlayerWithGameStatePlayerAlive(t *testing.T) {
@@ -424,7 +453,7 @@ func TestMovePlayerWithGameStatePlayerAlive(t *testing.T) {
 	playerId := "p1"
 	roomId := "room1"
 	pos := state.Position{X: 10, Y: 20, Angle: 0}
-	state.RegisterPlayer(playerId, roomId, nil)
+	state.RegisterPlayer(playerId, roomId, nil, "")
 	playerConn := state.GetPlayer(playerId)
 	// Simula que está en partida y vivo
 	gs := &state.GameState{
@@ -433,10 +462,10 @@ func TestMovePlayerWithGameStatePlayerAlive(t *testing.T) {
 		Bullets: map[string]*state.Bullet{},
 	}
 	playerConn.GameState = gs
-	localMockGameRepo.On("PublishToRoom", mock.Anything).Return()
+	localMockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 
 	gameService.MovePlayer(playerId, pos)
-	localMockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything)
+	localMockGameRepo.AssertCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestValidateRoomErrors(t *testing.T) {
@@ -474,6 +503,17 @@ func TestValidateRoomErrors(t *testing.T) {
 	err = gameService.ValidateRoom(room, "host")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "teams must have at most 1 player more than the other team")
+
+	room.Host = Player{ID: "p1"}
+	room.Team1 = []Player{{ID: "p1"}, {ID: "p2"}}
+	room.Team2 = []Player{{ID: "p3"}, {ID: "p4"}}
+	room.Ready = []string{"p2", "p3"}
+	err = gameService.ValidateRoom(room, "p1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not all players are ready")
+
+	room.SetReady("p4", true)
+	assert.NoError(t, gameService.ValidateRoom(room, "p1"))
 }
 
 func TestSendGameChangeMessageEmptyRoomId(t *testing.T) {
@@ -484,7 +524,7 @@ func TestSendGameChangeMessageEmptyRoomId(t *testing.T) {
 	gameService := NewGameService(localMockGameRepo, localMockRoomRepo, localRoomService, localUserService)
 
 	gameService.SendGameChangeMessage("", GameMessage{Type: "TEST", Payload: nil})
-	localMockGameRepo.AssertNotCalled(t, "PublishToRoom", mock.Anything)
+	localMockGameRepo.AssertNotCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestSendGameChangeMessageInvalidJSON(t *testing.T) {
@@ -497,7 +537,7 @@ func TestSendGameChangeMessageInvalidJSON(t *testing.T) {
 	msg := GameMessage{Type: "TEST", Payload: make(chan int)}
 
 	gameService.SendGameChangeMessage("room1", msg)
-	localMockGameRepo.AssertNotCalled(t, "PublishToRoom", mock.Anything)
+	localMockGameRepo.AssertNotCalled(t, "PublishToRoom", mock.Anything, mock.Anything)
 }
 
 func TestStartGameFailsOnGetRoom(t *testing.T) {
@@ -527,3 +567,154 @@ func TestStartGameFailsOnValidateRoom(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "not enough players")
 }
+
+func TestHandOffGamesReleasesLeadership(t *testing.T) {
+	localMockGameRepo := new(MockGameStateRepository)
+	localMockRoomRepo := new(MockRoomRepository)
+	gameService := NewGameService(localMockGameRepo, localMockRoomRepo, NewRoomService(localMockRoomRepo), userService)
+
+	gs := &state.GameState{
+		RoomId:  "roomHandoff",
+		Fence:   7,
+		Players: map[string]*state.PlayerState{},
+		Bullets: map[string]*state.Bullet{},
+	}
+	localMockGameRepo.On("SaveGameState", gs).Return(nil)
+	localMockGameRepo.On("RenewLeadership", "roomHandoff", int64(7), mock.Anything).Return(true, nil)
+	localMockGameRepo.On("ReleaseLeadership", "roomHandoff", int64(7)).Return(nil)
+	localMockGameRepo.On("SubscribeRoom", "roomHandoff").Return(nil)
+	localMockGameRepo.On("UnsubscribeRoom", "roomHandoff").Return(nil)
+	localMockGameRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
+	localMockGameRepo.On("PublishControl", mock.Anything).Return()
+
+	go gameService.RunGameLoop(gs, false)
+	require.Eventually(t, func() bool { return gameService.isLeading("roomHandoff") }, time.Second, 5*time.Millisecond)
+
+	gameService.HandOffGames()
+
+	assert.False(t, gameService.isLeading("roomHandoff"))
+	localMockGameRepo.AssertCalled(t, "UnsubscribeRoom", "roomHandoff")
+	localMockGameRepo.AssertCalled(t, "ReleaseLeadership", "roomHandoff", int64(7))
+	localMockGameRepo.AssertCalled(t, "PublishControl", mock.MatchedBy(func(payload string) bool {
+		return strings.Contains(payload, `"type":"GAME_HANDOFF"`) && strings.Contains(payload, `"fence":7`)
+	}))
+}
+
+func TestStartGameRefusedWhileDraining(t *testing.T) {
+	localMockGameRepo := NewMockGameStateRepository(t)
+	localMockRoomRepo := NewMockRoomRepository(t)
+	gameService := NewGameService(localMockGameRepo, localMockRoomRepo, NewRoomService(localMockRoomRepo), userService)
+
+	gameService.Drain(false)
+	err := gameService.StartGame("host", "roomX", true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "draining")
+	assert.True(t, gameService.IsDraining())
+
+	gameService.Resume()
+	assert.False(t, gameService.IsDraining())
+}
+
+func TestDrainLetsGamesFinishWithoutLivePeers(t *testing.T) {
+	localMockGameRepo := NewMockGameStateRepository(t)
+	localMockRoomRepo := NewMockRoomRepository(t)
+	gameService := NewGameService(localMockGameRepo, localMockRoomRepo, NewRoomService(localMockRoomRepo), userService)
+	registry := NewMemoryInstanceRegistry()
+	registry.Register(InstanceInfo{ID: instanceID, LastHeartbeat: time.Now()})
+	registry.Register(InstanceInfo{ID: "dead", LastHeartbeat: time.Now().Add(-time.Hour)})
+	registry.Register(InstanceInfo{ID: "drained", LastHeartbeat: time.Now(), Draining: true})
+	gameService.SetInstanceRegistry(registry)
+
+	assert.False(t, gameService.Drain(true))
+
+	registry.Register(InstanceInfo{ID: "peer", LastHeartbeat: time.Now()})
+	assert.True(t, gameService.Drain(true))
+}
+
+func TestStandbyKeepsWatchingRoomWhileDraining(t *testing.T) {
+	localMockGameRepo := NewMockGameStateRepository(t)
+	localMockRoomRepo := NewMockRoomRepository(t)
+	gameService := NewGameService(localMockGameRepo, localMockRoomRepo, NewRoomService(localMockRoomRepo), userService)
+	localMockRoomRepo.On("GetRoom", "drainRoom").Return(&Room{ID: "drainRoom", Status: "PLAYING"}, nil)
+
+	gameService.Drain(false)
+	assert.False(t, gameService.leadRoomIfPlaying("drainRoom"))
+	localMockGameRepo.AssertNotCalled(t, "TryToBecomeLeader", "drainRoom")
+}
+
+func TestSnapshotIncludesCheckpointOfPlayingRoom(t *testing.T) {
+	localMockGameRepo := new(MockGameStateRepository)
+	localMockRoomRepo := new(MockRoomRepository)
+	gameService := NewGameService(localMockGameRepo, localMockRoomRepo, NewRoomService(localMockRoomRepo), userService)
+
+	checkpoint := &state.GameState{RoomId: "playing", Tick: 12}
+	localMockRoomRepo.On("GetRoom", "lobby").Return(&Room{ID: "lobby", Status: "LOBBY"}, nil)
+	localMockRoomRepo.On("GetRoom", "playing").Return(&Room{ID: "playing", Status: "PLAYING"}, nil)
+	localMockGameRepo.On("RestoreGameState", "playing").Return(checkpoint, nil)
+
+	room, game, err := gameService.Snapshot("lobby")
+	assert.NoError(t, err)
+	assert.Equal(t, "lobby", room.ID)
+	assert.Nil(t, game)
+
+	room, game, err = gameService.Snapshot("playing")
+	assert.NoError(t, err)
+	assert.Equal(t, "playing", room.ID)
+	assert.Equal(t, checkpoint, game)
+	localMockGameRepo.AssertNotCalled(t, "RestoreGameState", "lobby")
+}
+
+func TestNotifyPlayerConnectionTellsTeammates(t *testing.T) {
+	localMockGameRepo := new(MockGameStateRepository)
+	localMockRoomRepo := new(MockRoomRepository)
+	gameService := NewGameService(localMockGameRepo, localMockRoomRepo, NewRoomService(localMockRoomRepo), userService)
+
+	state.RegisterPlayer("conn1", "connRoom", nil, "")
+	defer state.UnregisterPlayer("conn1")
+	localMockRoomRepo.On("GetRoom", "connRoom").Return(&Room{
+		ID:    "connRoom",
+		Team1: []Player{{ID: "conn2"}},
+		Team2: []Player{{ID: "conn3"}, {ID: "conn1"}, {ID: "conn4"}},
+	}, nil)
+	var published GameMessage
+	localMockGameRepo.On("PublishToRoom", "connRoom", mock.Anything).Run(func(args mock.Arguments) {
+		require.NoError(t, json.Unmarshal([]byte(args.String(1)), &published))
+	}).Return()
+
+	gameService.NotifyPlayerConnection("conn1", false)
+	assert.Equal(t, "PLAYER_DISCONNECTED", published.Type)
+	assert.Equal(t, []string{"conn3", "conn4"}, published.Users)
+
+	gameService.NotifyPlayerConnection("conn1", true)
+	assert.Equal(t, "PLAYER_RECONNECTED", published.Type)
+	assert.Equal(t, map[string]interface{}{"playerId": "conn1"}, published.Payload)
+}
+
+func TestGameLoopStopsWhenRoomIsRemoved(t *testing.T) {
+	bus := NewMemoryMessageBus()
+	roomRepo := NewMemoryRoomRepository(user.NewMockUserRepository(t), bus)
+	repo := NewMemoryGameStateRepository(nil, bus)
+	gameService := NewGameService(repo, roomRepo, NewRoomService(roomRepo), userService)
+	repo.SetLeaderElector(gameService)
+
+	room := &Room{ID: "removedRoom", Status: "PLAYING"}
+	require.NoError(t, roomRepo.SaveRoom(room))
+	token, ok := repo.TryToBecomeLeader(room.ID)
+	require.True(t, ok)
+	gs := &state.GameState{
+		RoomId:  room.ID,
+		Fence:   token,
+		Players: map[string]*state.PlayerState{},
+		Bullets: map[string]*state.Bullet{},
+	}
+
+	go gameService.RunGameLoop(gs, false)
+	require.Eventually(t, func() bool { return gameService.isLeading(room.ID) }, time.Second, 5*time.Millisecond)
+	require.NoError(t, roomRepo.DeleteRoom(room.ID))
+
+	require.Eventually(t, func() bool { return !gameService.isLeading(room.ID) }, 3*time.Second, 20*time.Millisecond)
+	_, err := repo.RestoreGameState(room.ID)
+	assert.Error(t, err)
+	leader, _ := repo.GetLeader(room.ID)
+	assert.Empty(t, leader)
+}
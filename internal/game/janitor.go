@@ -0,0 +1,260 @@
+package game
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+)
+
+// janitorStrikes is the number of consecutive sweeps a room or mapping must look orphaned
+// before it is removed, so rooms that are being created or left are never touched
+const janitorStrikes = 2
+
+// Janitor periodically removes the rooms and player mappings left behind by crashed
+// instances or by operations that failed midway
+type Janitor struct {
+	roomService *RoomService
+	roomRepo    RoomRepository
+	repo        GameStateRepository
+	registry    InstanceRegistry
+	bus         MessageBus
+	suspects    map[string]int
+}
+
+func NewJanitor(roomService *RoomService, roomRepo RoomRepository, repo GameStateRepository, registry InstanceRegistry, bus MessageBus) *Janitor {
+	return &Janitor{
+		roomService: roomService,
+		roomRepo:    roomRepo,
+		repo:        repo,
+		registry:    registry,
+		bus:         bus,
+		suspects:    make(map[string]int),
+	}
+}
+
+// janitorLock is the lock that makes a single instance of the cluster sweep at a time
+const janitorLock = "janitor"
+
+// Start runs a sweep every interval in the background. Only the instance holding the janitor
+// lock sweeps, it keeps the lock while it is alive and another instance takes over otherwise
+func (j *Janitor) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			locked, err := j.registry.TryLock(janitorLock, 2*interval)
+			if err != nil {
+				log.Println("[JANITOR] Error taking the janitor lock:", err)
+				continue
+			}
+			if locked {
+				j.Sweep()
+			}
+		}
+	}()
+}
+
+// Sweep checks every room and player mapping once and removes the ones that have been
+// orphaned for janitorStrikes sweeps
+func (j *Janitor) Sweep() {
+	seen := make(map[string]bool)
+	removed := 0
+
+	roomIds, err := j.roomRepo.GetRoomIDs()
+	if err != nil {
+		log.Println("[JANITOR] Error listing rooms:", err)
+		return
+	}
+	rooms := make(map[string]*Room, len(roomIds))
+	for _, roomId := range roomIds {
+		room, err := j.roomRepo.GetRoom(roomId)
+		var appErr *apperrors.AppError
+		if errors.As(err, &appErr) && appErr.Code == 404 {
+			if j.strike("room:"+roomId, seen) {
+				j.roomRepo.DeleteRoom(roomId)
+				log.Printf("[JANITOR] Removed room %s from the room list: room data not found", roomId)
+				removed++
+			}
+			continue
+		} else if err != nil {
+			log.Println("[JANITOR] Error getting room:", err)
+			continue
+		}
+		rooms[roomId] = room
+
+		if !hasConnectedPlayers(room) {
+			if j.strike("room:"+roomId, seen) {
+				j.removeRoom(room)
+				delete(rooms, roomId)
+				removed++
+			}
+			continue
+		}
+
+		if room.Status == "PLAYING" {
+			leader, err := j.repo.GetLeader(roomId)
+			if err != nil {
+				log.Println("[JANITOR] Error getting room leader:", err)
+				continue
+			}
+			if leader == "" && j.strike("leader:"+roomId, seen) {
+				j.resetRoom(roomId)
+				removed++
+			}
+		}
+	}
+
+	playerRooms, err := j.roomRepo.GetPlayerRooms()
+	if err != nil {
+		log.Println("[JANITOR] Error listing player rooms:", err)
+		return
+	}
+	for playerId, roomId := range playerRooms {
+		room := rooms[roomId]
+		if room != nil && roomHasPlayer(room, playerId) {
+			continue
+		}
+		if j.strike("player:"+playerId, seen) {
+			j.roomRepo.DeletePlayerRoom(playerId)
+			log.Printf("[JANITOR] Removed mapping of player %s to room %s: player is not in the room", playerId, roomId)
+			removed++
+		}
+	}
+
+	for key := range j.suspects {
+		if !seen[key] {
+			delete(j.suspects, key)
+		}
+	}
+	removed += j.pruneGroups()
+	if removed > 0 {
+		log.Printf("[JANITOR] Sweep finished, %d orphaned entries cleaned up", removed)
+	}
+}
+
+// strike counts one more sweep in which a key looked orphaned and reports if it must be removed
+func (j *Janitor) strike(key string, seen map[string]bool) bool {
+	seen[key] = true
+	j.suspects[key]++
+	if j.suspects[key] < janitorStrikes {
+		return false
+	}
+	delete(j.suspects, key)
+	return true
+}
+
+// removeRoom deletes a room nobody is connected to and the mappings of its players. A running
+// leader notices that its room is gone and stops the game itself
+func (j *Janitor) removeRoom(room *Room) {
+	for _, team := range [][]Player{room.Team1, room.Team2} {
+		for _, player := range team {
+			val, err := j.roomRepo.GetPlayerRoom(player.ID)
+			if err == nil && val == room.ID {
+				j.roomRepo.DeletePlayerRoom(player.ID)
+			}
+		}
+	}
+	if err := j.roomRepo.DeleteRoom(room.ID); err != nil {
+		log.Println("[JANITOR] Error deleting room:", err)
+		return
+	}
+	if room.Status == "PLAYING" {
+		j.deleteGame(room.ID)
+	}
+	log.Printf("[JANITOR] Removed room %s (%s, %d players): no connected players", room.ID, room.Status, room.Players)
+}
+
+// resetRoom sends a game that lost its leader back to the lobby and deletes its state
+func (j *Janitor) resetRoom(roomId string) {
+	room, err := retryOnConflict(func() (*Room, error) {
+		room, err := j.roomRepo.GetRoom(roomId)
+		if err != nil || room.Status != "PLAYING" {
+			return nil, err
+		}
+		room.Status = "LOBBY"
+		room.Ready = nil
+		return room, j.roomRepo.SaveRoom(room)
+	})
+	if err != nil {
+		log.Println("[JANITOR] Error saving room:", err)
+		return
+	}
+	if room == nil {
+		return
+	}
+	j.deleteGame(room.ID)
+	j.roomService.sendRoomChangeMessage(room, GameMessage{
+		Type:    "ROOM_INFO",
+		Payload: room,
+	})
+	log.Printf("[JANITOR] Reset room %s to LOBBY: game without leader", room.ID)
+}
+
+// deleteGame deletes the state of a game without leader. The janitor takes the leadership to do
+// it, so a leader elected in the meantime is never overridden; a game that still has a leader is
+// stopped by the leader once it sees that its room is gone or back in the lobby
+func (j *Janitor) deleteGame(roomId string) {
+	leader, err := j.repo.GetLeader(roomId)
+	if err != nil || leader != "" {
+		return
+	}
+	token, ok := j.repo.TryToBecomeLeader(roomId)
+	if !ok {
+		return
+	}
+	if err := j.repo.DeleteGameState(roomId, token); err != nil {
+		log.Println("[JANITOR] Error deleting game state:", err)
+	}
+	if err := j.repo.ReleaseLeadership(roomId, token); err != nil {
+		log.Println("[JANITOR] Error releasing leadership:", err)
+	}
+}
+
+// pruneGroups removes the consumer groups of the instances that are no longer alive
+func (j *Janitor) pruneGroups() int {
+	instances, err := j.registry.ListInstances()
+	if err != nil {
+		log.Println("[JANITOR] Error listing instances:", err)
+		return 0
+	}
+	alive := map[string]bool{instanceID: true}
+	for _, instance := range instances {
+		if instance.Alive {
+			alive[instance.ID] = true
+		}
+	}
+
+	pruned, err := j.bus.PruneGroups(alive)
+	if err != nil {
+		log.Println("[JANITOR] Error pruning consumer groups:", err)
+	}
+	if pruned > 0 {
+		log.Printf("[JANITOR] Removed %d consumer groups of dead instances", pruned)
+	}
+	return pruned
+}
+
+func hasConnectedPlayers(room *Room) bool {
+	for _, team := range [][]Player{room.Team1, room.Team2} {
+		for _, player := range team {
+			if state.IsPlayerConnected(player.ID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func roomHasPlayer(room *Room, playerId string) bool {
+	for _, team := range [][]Player{room.Team1, room.Team2} {
+		for _, player := range team {
+			if player.ID == playerId {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+	"github.com/thesrcielos/TopTankBattle/internal/user"
+)
+
+func newTestJanitor(t *testing.T) (*Janitor, *MemoryRoomRepository, *MemoryGameStateRepository) {
+	userRepo := user.NewMockUserRepository(t)
+	userRepo.On("GetUserUsername", 1).Return("host", nil).Maybe()
+	bus := NewMemoryMessageBus()
+	roomRepo := NewMemoryRoomRepository(userRepo, bus)
+	repo := NewMemoryGameStateRepository(nil, bus)
+	return NewJanitor(NewRoomService(roomRepo), roomRepo, repo, NewMemoryInstanceRegistry(), bus), roomRepo, repo
+}
+
+func TestJanitorRemovesRoomWithoutConnectedPlayers(t *testing.T) {
+	janitor, roomRepo, _ := newTestJanitor(t)
+	room, _ := roomRepo.SaveRoomRequest(&RoomRequest{Name: "room", Player: 1, Capacity: 2})
+	roomRepo.SavePlayerRoom(&PlayerRequest{Player: "1", Room: room.ID})
+
+	janitor.Sweep()
+	_, err := roomRepo.GetRoom(room.ID)
+	assert.NoError(t, err)
+
+	janitor.Sweep()
+	_, err = roomRepo.GetRoom(room.ID)
+	assert.Error(t, err)
+	ids, _ := roomRepo.GetRoomIDs()
+	assert.Empty(t, ids)
+	val, _ := roomRepo.GetPlayerRoom("1")
+	assert.Nil(t, val)
+}
+
+func TestJanitorKeepsRoomWithConnectedPlayer(t *testing.T) {
+	janitor, roomRepo, _ := newTestJanitor(t)
+	room, _ := roomRepo.SaveRoomRequest(&RoomRequest{Name: "room", Player: 1, Capacity: 2})
+	state.RegisterPlayer("1", room.ID, nil, "")
+	defer state.UnregisterPlayer("1")
+
+	janitor.Sweep()
+	janitor.Sweep()
+
+	_, err := roomRepo.GetRoom(room.ID)
+	assert.NoError(t, err)
+}
+
+func TestJanitorResetsPlayingRoomWithoutLeader(t *testing.T) {
+	janitor, roomRepo, repo := newTestJanitor(t)
+	room, _ := roomRepo.SaveRoomRequest(&RoomRequest{Name: "room", Player: 1, Capacity: 2})
+	room.Status = "PLAYING"
+	roomRepo.SaveRoom(room)
+	state.RegisterPlayer("1", room.ID, nil, "")
+	defer state.UnregisterPlayer("1")
+
+	repo.TryToBecomeLeader(room.ID)
+	janitor.Sweep()
+	janitor.Sweep()
+	saved, _ := roomRepo.GetRoom(room.ID)
+	assert.Equal(t, "PLAYING", saved.Status)
+
+	repo.SaveGameState(&state.GameState{RoomId: room.ID, Fence: 1})
+	repo.ReleaseLeadership(room.ID, 1)
+	janitor.Sweep()
+	janitor.Sweep()
+	saved, _ = roomRepo.GetRoom(room.ID)
+	assert.Equal(t, "LOBBY", saved.Status)
+	_, err := repo.RestoreGameState(room.ID)
+	assert.Error(t, err)
+	leader, _ := repo.GetLeader(room.ID)
+	assert.Empty(t, leader)
+}
+
+func TestJanitorDeletesGameOfRemovedPlayingRoom(t *testing.T) {
+	janitor, roomRepo, repo := newTestJanitor(t)
+	room, _ := roomRepo.SaveRoomRequest(&RoomRequest{Name: "room", Player: 1, Capacity: 2})
+	room.Status = "PLAYING"
+	roomRepo.SaveRoom(room)
+	token, _ := repo.TryToBecomeLeader(room.ID)
+	repo.SaveGameState(&state.GameState{RoomId: room.ID, Fence: token})
+	repo.ReleaseLeadership(room.ID, token)
+
+	janitor.Sweep()
+	janitor.Sweep()
+
+	_, err := roomRepo.GetRoom(room.ID)
+	assert.Error(t, err)
+	_, err = repo.RestoreGameState(room.ID)
+	assert.Error(t, err)
+}
+
+func TestJanitorRemovesDanglingPlayerMapping(t *testing.T) {
+	janitor, roomRepo, _ := newTestJanitor(t)
+	roomRepo.SavePlayerRoom(&PlayerRequest{Player: "7", Room: "missing"})
+
+	janitor.Sweep()
+	val, _ := roomRepo.GetPlayerRoom("7")
+	assert.Equal(t, "missing", val)
+
+	janitor.Sweep()
+	val, _ = roomRepo.GetPlayerRoom("7")
+	assert.Nil(t, val)
+}
+
+func TestJanitorPrunesGroupsOfDeadInstances(t *testing.T) {
+	userRepo := user.NewMockUserRepository(t)
+	roomRepo := NewMemoryRoomRepository(userRepo, NewMemoryMessageBus())
+	registry := NewMemoryInstanceRegistry()
+	registry.Register(InstanceInfo{ID: "alive", LastHeartbeat: time.Now()})
+	registry.Register(InstanceInfo{ID: "dead", LastHeartbeat: time.Now().Add(-time.Hour)})
+	bus := NewMockMessageBus(t)
+	bus.On("PruneGroups", map[string]bool{instanceID: true, "alive": true}).Return(1, nil)
+	janitor := NewJanitor(NewRoomService(roomRepo), roomRepo, NewMemoryGameStateRepository(nil, NewMemoryMessageBus()), registry, bus)
+
+	janitor.Sweep()
+
+	bus.AssertExpectations(t)
+}
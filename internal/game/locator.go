@@ -0,0 +1,68 @@
+package game
+
+import (
+	"log"
+
+	"github.com/thesrcielos/TopTankBattle/websocket/transport"
+)
+
+// LeaderHint tells a client which instance runs the game of its room, so it can reconnect
+// there and skip the hop through the message bus
+type LeaderHint struct {
+	RoomId   string `json:"roomId"`
+	Instance string `json:"instance"`
+	Address  string `json:"address"`
+}
+
+// LeaderLocator finds the instance leading a room from the leader key and the instance registry
+type LeaderLocator struct {
+	repo     GameStateRepository
+	registry InstanceRegistry
+}
+
+func NewLeaderLocator(repo GameStateRepository, registry InstanceRegistry) *LeaderLocator {
+	return &LeaderLocator{repo: repo, registry: registry}
+}
+
+// LeaderHint returns where the game of a room runs when it is led by another live instance
+func (l *LeaderLocator) LeaderHint(roomId string) (*LeaderHint, bool) {
+	leader, err := l.repo.GetLeader(roomId)
+	if err != nil {
+		log.Println("Error getting room leader:", err)
+		return nil, false
+	}
+	if leader == "" || leader == instanceID {
+		return nil, false
+	}
+
+	instances, err := l.registry.ListInstances()
+	if err != nil {
+		log.Println("Error listing instances:", err)
+		return nil, false
+	}
+	for _, instance := range instances {
+		if instance.ID == leader && instance.Alive && instance.Address != "" {
+			return &LeaderHint{
+				RoomId:   roomId,
+				Instance: instance.ID,
+				Address:  instance.Address,
+			}, true
+		}
+	}
+	return nil, false
+}
+
+// Redirect tells the local players of a room to reconnect to its leader when it runs elsewhere
+func (l *LeaderLocator) Redirect(roomId string, players []string) {
+	hint, ok := l.LeaderHint(roomId)
+	if !ok {
+		return
+	}
+	msg := transport.OutgoingMessage{
+		Type:    "REDIRECT",
+		Payload: hint,
+	}
+	for _, playerId := range players {
+		transport.SendToPlayer(playerId, msg)
+	}
+}
@@ -0,0 +1,34 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeaderHintPointsToLiveLeader(t *testing.T) {
+	repo := NewMockGameStateRepository(t)
+	registry := NewMemoryInstanceRegistry()
+	registry.Register(InstanceInfo{ID: "other", Address: "10.0.0.2:8080", LastHeartbeat: time.Now()})
+	repo.On("GetLeader", "room1").Return("other", nil)
+
+	hint, ok := NewLeaderLocator(repo, registry).LeaderHint("room1")
+	assert.True(t, ok)
+	assert.Equal(t, &LeaderHint{RoomId: "room1", Instance: "other", Address: "10.0.0.2:8080"}, hint)
+}
+
+func TestLeaderHintSkipsLocalAndDeadLeaders(t *testing.T) {
+	repo := NewMockGameStateRepository(t)
+	registry := NewMemoryInstanceRegistry()
+	registry.Register(InstanceInfo{ID: "dead", Address: "10.0.0.3:8080", LastHeartbeat: time.Now().Add(-time.Hour)})
+	repo.On("GetLeader", "local").Return(instanceID, nil)
+	repo.On("GetLeader", "dead").Return("dead", nil)
+	repo.On("GetLeader", "lobby").Return("", nil)
+	locator := NewLeaderLocator(repo, registry)
+
+	for _, roomId := range []string{"local", "dead", "lobby"} {
+		_, ok := locator.LeaderHint(roomId)
+		assert.False(t, ok, roomId)
+	}
+}
@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 var Tmap Map
 var Matrix [][]bool
 
+// ID identifies the loaded map, it's the name of its file without the extension
+var ID string
+
 func getAppDir() string {
 	exe, _ := os.Executable()
 	return filepath.Dir(exe)
@@ -42,6 +46,7 @@ func GenerateCollisionMatrix(path string) error {
 		fmt.Printf("Error reading map: %v\n", err)
 		return err
 	}
+	ID = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
 	Matrix = make([][]bool, Tmap.Height)
 	for i := range Matrix {
 		Matrix[i] = make([]bool, Tmap.Width)
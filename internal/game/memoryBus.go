@@ -0,0 +1,101 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+type busMessage struct {
+	topic   string
+	payload string
+}
+
+// MemoryMessageBus is an in-process MessageBus for single node deployments and tests.
+// Messages are delivered in publish order from a single goroutine, like a Redis subscriber.
+type MemoryMessageBus struct {
+	handlers map[string]MessageHandler
+	mu       sync.RWMutex
+	queue    []busMessage
+	queueMu  sync.Mutex
+	wake     chan struct{}
+	since    time.Time
+}
+
+func NewMemoryMessageBus() *MemoryMessageBus {
+	b := &MemoryMessageBus{
+		handlers: make(map[string]MessageHandler),
+		wake:     make(chan struct{}, 1),
+		since:    time.Now(),
+	}
+	go b.deliver()
+	return b
+}
+
+func (b *MemoryMessageBus) deliver() {
+	for range b.wake {
+		for {
+			msg, ok := b.next()
+			if !ok {
+				break
+			}
+			b.mu.RLock()
+			handler := b.handlers[msg.topic]
+			b.mu.RUnlock()
+			if handler != nil {
+				dispatch(handler, msg.payload)
+			}
+		}
+	}
+}
+
+// next takes the oldest queued message
+func (b *MemoryMessageBus) next() (busMessage, bool) {
+	b.queueMu.Lock()
+	defer b.queueMu.Unlock()
+	if len(b.queue) == 0 {
+		return busMessage{}, false
+	}
+	msg := b.queue[0]
+	b.queue = b.queue[1:]
+	return msg, true
+}
+
+// Publish queues a message without blocking. The queue is unbounded so no event is ever lost,
+// like a Redis stream keeps the events its readers have not reached yet
+func (b *MemoryMessageBus) Publish(topic string, payload string) error {
+	b.queueMu.Lock()
+	b.queue = append(b.queue, busMessage{topic: topic, payload: payload})
+	b.queueMu.Unlock()
+
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+func (b *MemoryMessageBus) Subscribe(topic string, handler MessageHandler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[topic] = handler
+	return nil
+}
+
+func (b *MemoryMessageBus) Unsubscribe(topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.handlers, topic)
+	return nil
+}
+
+// SetRecoveryHandler does nothing, the in-memory bus never disconnects
+func (b *MemoryMessageBus) SetRecoveryHandler(handler func()) {}
+
+func (b *MemoryMessageBus) Health() BusHealth {
+	return BusHealth{Connected: true, Since: b.since}
+}
+
+// PruneGroups does nothing, the in-memory bus has no consumer groups
+func (b *MemoryMessageBus) PruneGroups(alive map[string]bool) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryMessageBusDeliversInOrder(t *testing.T) {
+	bus := NewMemoryMessageBus()
+	received := make(chan string, 3)
+	bus.Subscribe("room:1:messages", func(payload string) {
+		received <- payload
+	})
+
+	bus.Publish("room:1:messages", "a")
+	bus.Publish("room:2:messages", "ignored")
+	bus.Publish("room:1:messages", "b")
+
+	assert.Equal(t, "a", <-received)
+	assert.Equal(t, "b", <-received)
+}
+
+func TestMemoryMessageBusUnsubscribe(t *testing.T) {
+	bus := NewMemoryMessageBus()
+	received := make(chan string, 1)
+	bus.Subscribe("room:1:messages", func(payload string) {
+		received <- payload
+	})
+	bus.Unsubscribe("room:1:messages")
+
+	bus.Publish("room:1:messages", "a")
+
+	select {
+	case payload := <-received:
+		t.Fatalf("unexpected message %s", payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMemoryMessageBusKeepsEveryMessageOfABurst(t *testing.T) {
+	bus := NewMemoryMessageBus()
+	const burst = 5000
+	received := make(chan int, 1)
+	count := 0
+	bus.Subscribe("room:2:messages", func(payload string) {
+		count++
+		if count == burst {
+			received <- count
+		}
+	})
+	bus.Subscribe("room:1:messages", func(payload string) {
+		for i := 0; i < burst; i++ {
+			assert.NoError(t, bus.Publish("room:2:messages", "fan out"))
+		}
+	})
+
+	bus.Publish("room:1:messages", "a")
+
+	select {
+	case n := <-received:
+		assert.Equal(t, burst, n)
+	case <-time.After(time.Second):
+		t.Fatal("messages published from a handler were lost or blocked the bus")
+	}
+}
@@ -0,0 +1,66 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryChatHistory struct {
+	messages []ChatMessage
+	updated  time.Time
+}
+
+// MemoryChatRepository keeps the chat history of the rooms in the process memory
+type MemoryChatRepository struct {
+	histories map[string]*memoryChatHistory
+	mu        sync.Mutex
+}
+
+func NewMemoryChatRepository() *MemoryChatRepository {
+	return &MemoryChatRepository{histories: make(map[string]*memoryChatHistory)}
+}
+
+func (r *MemoryChatRepository) AppendMessage(roomId string, message ChatMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := r.history(roomId, time.Now())
+	if history == nil {
+		history = &memoryChatHistory{}
+		r.histories[roomId] = history
+	}
+	history.messages = append(history.messages, message)
+	if len(history.messages) > chatHistorySize {
+		history.messages = history.messages[len(history.messages)-chatHistorySize:]
+	}
+	history.updated = time.Now()
+	return nil
+}
+
+func (r *MemoryChatRepository) GetHistory(roomId string) ([]ChatMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	history := r.history(roomId, time.Now())
+	if history == nil {
+		return []ChatMessage{}, nil
+	}
+	return append([]ChatMessage{}, history.messages...), nil
+}
+
+// DeleteHistory forgets the history of a room
+func (r *MemoryChatRepository) DeleteHistory(roomId string) {
+	r.mu.Lock()
+	delete(r.histories, roomId)
+	r.mu.Unlock()
+}
+
+// history returns the history of a room, forgetting it when it expired like its Redis key would
+func (r *MemoryChatRepository) history(roomId string, now time.Time) *memoryChatHistory {
+	history, ok := r.histories[roomId]
+	if ok && now.Sub(history.updated) > chatHistoryRetention {
+		delete(r.histories, roomId)
+		return nil
+	}
+	return history
+}
@@ -0,0 +1,45 @@
+package game
+
+import (
+	"sync"
+	"time"
+)
+
+// MemoryInstanceRegistry is the registry of a single node deployment
+type MemoryInstanceRegistry struct {
+	instances map[string]InstanceInfo
+	mu        sync.Mutex
+}
+
+func NewMemoryInstanceRegistry() *MemoryInstanceRegistry {
+	return &MemoryInstanceRegistry{instances: make(map[string]InstanceInfo)}
+}
+
+func (r *MemoryInstanceRegistry) Register(info InstanceInfo) error {
+	r.mu.Lock()
+	r.instances[info.ID] = info
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *MemoryInstanceRegistry) Deregister(instanceId string) error {
+	r.mu.Lock()
+	delete(r.instances, instanceId)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *MemoryInstanceRegistry) ListInstances() ([]InstanceInfo, error) {
+	r.mu.Lock()
+	instances := make([]InstanceInfo, 0, len(r.instances))
+	for _, info := range r.instances {
+		instances = append(instances, info)
+	}
+	r.mu.Unlock()
+	return withLiveness(instances, time.Now()), nil
+}
+
+// TryLock always succeeds, a single node has nobody to share its tasks with
+func (r *MemoryInstanceRegistry) TryLock(name string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
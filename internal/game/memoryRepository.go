@@ -0,0 +1,338 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/internal/user"
+)
+
+// MemoryRoomRepository keeps rooms in the process memory for single node runs. Rooms are
+// stored serialized so callers never share a *Room with the repository, like with Redis.
+type MemoryRoomRepository struct {
+	userRepository user.UserRepository
+	bus            MessageBus
+	rooms          map[string][]byte
+	roomIDs        []string
+	playerRooms    map[string]string
+	chat           *MemoryChatRepository
+	mu             sync.RWMutex
+}
+
+func NewMemoryRoomRepository(userRepo user.UserRepository, bus MessageBus) *MemoryRoomRepository {
+	return &MemoryRoomRepository{
+		userRepository: userRepo,
+		bus:            bus,
+		rooms:          make(map[string][]byte),
+		playerRooms:    make(map[string]string),
+	}
+}
+
+// SetChatRepository sets the chat history deleted along with the rooms, like the Redis repository
+// deletes the chat key of a room
+func (r *MemoryRoomRepository) SetChatRepository(chat *MemoryChatRepository) {
+	r.mu.Lock()
+	r.chat = chat
+	r.mu.Unlock()
+}
+
+func (r *MemoryRoomRepository) SaveRoomRequest(RoomRequest *RoomRequest) (*Room, error) {
+	player, errDB := r.CreatePlayer(RoomRequest.Player)
+	if errDB != nil {
+		return nil, apperrors.NewAppError(500, "Error creating player", errDB)
+	}
+
+	key := uuid.New().String()[:8]
+	room := &Room{
+		ID:       key,
+		Name:     RoomRequest.Name,
+		Capacity: RoomRequest.Capacity,
+		Players:  1,
+		Team1:    []Player{*player},
+		Team2:    []Player{},
+		Host:     *player,
+		Status:   "LOBBY",
+	}
+
+	if err := r.SaveRoom(room); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.roomIDs = append(r.roomIDs, room.ID)
+	r.mu.Unlock()
+
+	return room, nil
+}
+
+// SaveRoom stores a room and increases its version, with the same conflict rules as Redis
+func (r *MemoryRoomRepository) SaveRoom(room *Room) error {
+	next := *room
+	next.Version++
+	data, err := json.Marshal(next)
+	if err != nil {
+		return apperrors.NewAppError(500, "Error serializing room data", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.rooms[room.ID]; ok {
+		var stored Room
+		if err := json.Unmarshal(current, &stored); err != nil {
+			return apperrors.NewAppError(500, "Error unmarshalling room data", err)
+		}
+		if stored.Version != room.Version {
+			return ErrRoomConflict
+		}
+	} else if room.Version != 0 {
+		return apperrors.NewAppError(404, "Room not found", errors.New("room not found"))
+	}
+	r.rooms[room.ID] = data
+
+	room.Version = next.Version
+	return nil
+}
+
+func (r *MemoryRoomRepository) SavePlayerRoom(playerRequest *PlayerRequest) error {
+	r.mu.Lock()
+	r.playerRooms[playerRequest.Player] = playerRequest.Room
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *MemoryRoomRepository) GetPlayerRoom(playerId string) (interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	val, ok := r.playerRooms[playerId]
+	if !ok {
+		return nil, nil
+	}
+	return val, nil
+}
+
+func (r *MemoryRoomRepository) DeletePlayerRoom(playerId string) error {
+	r.mu.Lock()
+	delete(r.playerRooms, playerId)
+	r.mu.Unlock()
+
+	return nil
+}
+
+func (r *MemoryRoomRepository) GetRoom(key string) (*Room, error) {
+	r.mu.RLock()
+	data, ok := r.rooms[key]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, apperrors.NewAppError(404, "Room not found", errors.New("room not found"))
+	}
+
+	var room Room
+	if err := json.Unmarshal(data, &room); err != nil {
+		return nil, apperrors.NewAppError(500, "Error unmarshalling room data", err)
+	}
+
+	return &room, nil
+}
+
+// GetRooms returns the rooms newest first, like the sorted set used by the Redis repository
+func (r *MemoryRoomRepository) GetRooms(page, pageSize int) (*[]Room, error) {
+	r.mu.RLock()
+	ids := make([]string, 0, len(r.roomIDs))
+	for i := len(r.roomIDs) - 1; i >= 0; i-- {
+		ids = append(ids, r.roomIDs[i])
+	}
+	r.mu.RUnlock()
+
+	start := page * pageSize
+	end := start + pageSize
+	if start < 0 || start > len(ids) {
+		start = len(ids)
+	}
+	if end > len(ids) {
+		end = len(ids)
+	}
+	if end < start {
+		end = start
+	}
+
+	rooms := []Room{}
+	for _, id := range ids[start:end] {
+		room, err := r.GetRoom(id)
+		if err != nil {
+			return nil, apperrors.NewAppError(500, "Error getting room by ID", err)
+		}
+		rooms = append(rooms, *room)
+	}
+
+	return &rooms, nil
+}
+
+func (r *MemoryRoomRepository) CreatePlayer(id int) (*Player, error) {
+	username, errDB := r.userRepository.GetUserUsername(id)
+	if errDB != nil {
+		return nil, errDB
+	}
+
+	player := Player{
+		ID:       strconv.Itoa(id),
+		Username: username,
+	}
+
+	return &player, nil
+}
+
+func (r *MemoryRoomRepository) AddPlayer(playerRequest *PlayerRequest) (*Room, error) {
+	room, err := r.GetRoom(playerRequest.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.Capacity == room.Players {
+		return nil, apperrors.NewAppError(400, "Room is full", nil)
+	}
+
+	userId, err := strconv.Atoi(playerRequest.Player)
+	if err != nil {
+		return nil, apperrors.NewAppError(400, "Invalid player ID", err)
+	}
+	player, errDB := r.CreatePlayer(userId)
+	if errDB != nil {
+		return nil, apperrors.NewAppError(500, "Error creating player", errDB)
+	}
+
+	if len(room.Team1) <= len(room.Team2) {
+		room.Team1 = append(room.Team1, *player)
+	} else {
+		room.Team2 = append(room.Team2, *player)
+	}
+	room.Players += 1
+
+	if err := r.SaveRoom(room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+func (r *MemoryRoomRepository) RemovePlayer(req *PlayerRequest) (*Room, error) {
+	room, err := r.GetRoom(req.Room)
+	if err != nil {
+		return nil, err
+	}
+
+	if room.Players == 0 {
+		return nil, apperrors.NewAppError(400, "Room is empty", errors.New("room is empty"))
+	}
+	if !roomHasPlayer(room, req.Player) {
+		return nil, ErrPlayerNotInRoom
+	}
+
+	room.Team1 = withoutPlayer(room.Team1, req.Player)
+	room.Team2 = withoutPlayer(room.Team2, req.Player)
+	room.SetReady(req.Player, false)
+	room.Players -= 1
+	if err := r.SaveRoom(room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+func withoutPlayer(team []Player, playerId string) []Player {
+	players := make([]Player, 0, len(team))
+	for _, p := range team {
+		if p.ID != playerId {
+			players = append(players, p)
+		}
+	}
+	return players
+}
+
+func (r *MemoryRoomRepository) ChangeRoomOwner(roomId string, player Player) (*Room, error) {
+	room, err := r.GetRoom(roomId)
+	if err != nil {
+		return nil, err
+	}
+
+	room.Host = player
+
+	if err := r.SaveRoom(room); err != nil {
+		return nil, err
+	}
+
+	return room, nil
+}
+
+func (r *MemoryRoomRepository) DeleteRoom(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deleteRoom(id)
+	return nil
+}
+
+// deleteRoom removes a room and its chat history. The caller must hold mu.
+func (r *MemoryRoomRepository) deleteRoom(id string) {
+	delete(r.rooms, id)
+	for i, roomId := range r.roomIDs {
+		if roomId == id {
+			r.roomIDs = append(r.roomIDs[:i], r.roomIDs[i+1:]...)
+			break
+		}
+	}
+	if r.chat != nil {
+		r.chat.DeleteHistory(id)
+	}
+}
+
+// DeleteLobbyRoom removes a room that is still in the lobby. It fails with ErrRoomConflict if the
+// room was saved by someone else since it was read.
+func (r *MemoryRoomRepository) DeleteLobbyRoom(room *Room) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.rooms[room.ID]
+	if !ok {
+		return apperrors.NewAppError(404, "Room not found", errors.New("room not found"))
+	}
+	var stored Room
+	if err := json.Unmarshal(current, &stored); err != nil {
+		return apperrors.NewAppError(500, "Error unmarshalling room data", err)
+	}
+	if stored.Version != room.Version || stored.Status != "LOBBY" {
+		return ErrRoomConflict
+	}
+	r.deleteRoom(room.ID)
+	return nil
+}
+
+func (r *MemoryRoomRepository) PublishToRoom(roomId string, payload string) {
+	if err := r.bus.Publish(roomChannel(roomId), payload); err != nil {
+		log.Println("Error publishing to room updates channel:", err)
+	}
+}
+
+func (r *MemoryRoomRepository) GetRoomIDs() ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]string, len(r.roomIDs))
+	copy(ids, r.roomIDs)
+	return ids, nil
+}
+
+func (r *MemoryRoomRepository) GetPlayerRooms() (map[string]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	playerRooms := make(map[string]string, len(r.playerRooms))
+	for playerId, roomId := range r.playerRooms {
+		playerRooms[playerId] = roomId
+	}
+	return playerRooms, nil
+}
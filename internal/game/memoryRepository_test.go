@@ -0,0 +1,122 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+	"github.com/thesrcielos/TopTankBattle/internal/user"
+)
+
+func TestMemoryRoomRepositoryGetRoomsNewestFirst(t *testing.T) {
+	userRepo := user.NewMockUserRepository(t)
+	userRepo.On("GetUserUsername", 1).Return("host", nil)
+	repo := NewMemoryRoomRepository(userRepo, NewMemoryMessageBus())
+
+	first, err := repo.SaveRoomRequest(&RoomRequest{Name: "first", Player: 1, Capacity: 2})
+	assert.NoError(t, err)
+	second, err := repo.SaveRoomRequest(&RoomRequest{Name: "second", Player: 1, Capacity: 2})
+	assert.NoError(t, err)
+
+	page, err := repo.GetRooms(0, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{second.ID}, roomIDs(*page))
+
+	page, err = repo.GetRooms(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{first.ID}, roomIDs(*page))
+
+	page, err = repo.GetRooms(2, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, *page)
+}
+
+func TestMemoryRoomRepositoryRoomNotFound(t *testing.T) {
+	repo := NewMemoryRoomRepository(user.NewMockUserRepository(t), NewMemoryMessageBus())
+
+	room, err := repo.GetRoom("missing")
+	assert.Nil(t, room)
+	appErr, ok := err.(*apperrors.AppError)
+	assert.True(t, ok)
+	assert.Equal(t, 404, appErr.Code)
+}
+
+func TestMemoryRoomRepositoryAddAndRemovePlayer(t *testing.T) {
+	userRepo := user.NewMockUserRepository(t)
+	userRepo.On("GetUserUsername", 1).Return("host", nil)
+	userRepo.On("GetUserUsername", 2).Return("guest", nil)
+	repo := NewMemoryRoomRepository(userRepo, NewMemoryMessageBus())
+	room, _ := repo.SaveRoomRequest(&RoomRequest{Name: "room", Player: 1, Capacity: 2})
+
+	room, err := repo.AddPlayer(&PlayerRequest{Player: "2", Room: room.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, room.Players)
+	assert.Equal(t, "guest", room.Team2[0].Username)
+
+	_, err = repo.AddPlayer(&PlayerRequest{Player: "3", Room: room.ID})
+	assert.EqualError(t, err, "Room is full")
+
+	room, err = repo.RemovePlayer(&PlayerRequest{Player: "2", Room: room.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, room.Players)
+	assert.Empty(t, room.Team2)
+
+	_, err = repo.RemovePlayer(&PlayerRequest{Player: "2", Room: room.ID})
+	assert.ErrorIs(t, err, ErrPlayerNotInRoom)
+}
+
+func TestMemoryGameStateRepositoryLeadership(t *testing.T) {
+	repo := NewMemoryGameStateRepository(nil, NewMemoryMessageBus())
+
+	token, ok := repo.TryToBecomeLeader("room1")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), token)
+
+	again, ok := repo.TryToBecomeLeader("room1")
+	assert.True(t, ok)
+	assert.Equal(t, token, again)
+
+	renewed, err := repo.RenewLeadership("room1", token+1, leaderExpiration)
+	assert.NoError(t, err)
+	assert.False(t, renewed)
+
+	assert.NoError(t, repo.ReleaseLeadership("room1", token))
+	next, ok := repo.TryToBecomeLeader("room1")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), next)
+
+	game := &state.GameState{RoomId: "room1", Fence: token, Players: map[string]*state.PlayerState{}}
+	assert.Equal(t, ErrStaleLeader, repo.SaveGameState(game))
+
+	game.Fence = next
+	game.Tick = 7
+	assert.NoError(t, repo.SaveGameState(game))
+	restored, err := repo.RestoreGameState("room1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), restored.Tick)
+}
+
+func TestMemoryRoomRepositorySaveRoomRejectsStaleVersion(t *testing.T) {
+	userRepo := user.NewMockUserRepository(t)
+	userRepo.On("GetUserUsername", 1).Return("host", nil)
+	repo := NewMemoryRoomRepository(userRepo, NewMemoryMessageBus())
+	room, _ := repo.SaveRoomRequest(&RoomRequest{Name: "room", Player: 1, Capacity: 2})
+
+	first, _ := repo.GetRoom(room.ID)
+	second, _ := repo.GetRoom(room.ID)
+	first.Status = "PLAYING"
+	assert.NoError(t, repo.SaveRoom(first))
+	assert.Equal(t, 2, first.Version)
+
+	second.Name = "renamed"
+	assert.ErrorIs(t, repo.SaveRoom(second), ErrRoomConflict)
+}
+
+func roomIDs(rooms []Room) []string {
+	ids := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		ids = append(ids, room.ID)
+	}
+	return ids
+}
@@ -0,0 +1,152 @@
+package game
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+)
+
+type memoryLease struct {
+	owner   string
+	token   int64
+	expires time.Time
+}
+
+type memoryCheckpoint struct {
+	data    []byte
+	expires time.Time
+}
+
+// MemoryGameStateRepository keeps leaderships and checkpoints in the process memory
+type MemoryGameStateRepository struct {
+	*roomEvents
+	leases      map[string]memoryLease
+	tokens      map[string]int64
+	checkpoints map[string]memoryCheckpoint
+	mu          sync.Mutex
+}
+
+func NewMemoryGameStateRepository(leaderElector LeaderElector, bus MessageBus) *MemoryGameStateRepository {
+	return &MemoryGameStateRepository{
+		roomEvents:  newRoomEvents(leaderElector, bus),
+		leases:      make(map[string]memoryLease),
+		tokens:      make(map[string]int64),
+		checkpoints: make(map[string]memoryCheckpoint),
+	}
+}
+
+// currentLease returns the lease of a room if it has not expired. The caller must hold mu.
+func (r *MemoryGameStateRepository) currentLease(roomID string) (memoryLease, bool) {
+	lease, ok := r.leases[roomID]
+	if !ok || time.Now().After(lease.expires) {
+		return memoryLease{}, false
+	}
+	return lease, true
+}
+
+func (r *MemoryGameStateRepository) TryToBecomeLeader(roomID string) (int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.currentLease(roomID); ok {
+		if lease.owner != instanceID {
+			return 0, false
+		}
+		lease.expires = time.Now().Add(leaderExpiration)
+		r.leases[roomID] = lease
+		return lease.token, true
+	}
+
+	r.tokens[roomID]++
+	r.leases[roomID] = memoryLease{
+		owner:   instanceID,
+		token:   r.tokens[roomID],
+		expires: time.Now().Add(leaderExpiration),
+	}
+	return r.tokens[roomID], true
+}
+
+func (r *MemoryGameStateRepository) SaveGameState(gameState *state.GameState) error {
+	data, err := state.NewCheckpoint(gameState).Encode()
+	if err != nil {
+		return apperrors.NewAppError(500, "Error encoding game checkpoint", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if gameState.Fence < r.tokens[gameState.RoomId] {
+		return ErrStaleLeader
+	}
+	r.checkpoints[gameState.RoomId] = memoryCheckpoint{
+		data:    data,
+		expires: time.Now().Add(checkpointExpiration),
+	}
+
+	return nil
+}
+
+func (r *MemoryGameStateRepository) RestoreGameState(roomID string) (*state.GameState, error) {
+	r.mu.Lock()
+	saved, ok := r.checkpoints[roomID]
+	r.mu.Unlock()
+	if !ok || time.Now().After(saved.expires) {
+		return nil, apperrors.NewAppError(404, "Game checkpoint not found", errors.New("checkpoint not found"))
+	}
+
+	checkpoint, err := state.DecodeCheckpoint(saved.data)
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error restoring game checkpoint", err)
+	}
+
+	return checkpoint.GameState(), nil
+}
+
+func (r *MemoryGameStateRepository) RenewLeadership(roomID string, token int64, expiration time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lease, ok := r.currentLease(roomID)
+	if !ok || lease.owner != instanceID || lease.token != token {
+		return false, nil
+	}
+	lease.expires = time.Now().Add(expiration)
+	r.leases[roomID] = lease
+	return true, nil
+}
+
+func (r *MemoryGameStateRepository) ReleaseLeadership(roomID string, token int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if lease, ok := r.currentLease(roomID); ok && lease.owner == instanceID && lease.token == token {
+		delete(r.leases, roomID)
+	}
+	return nil
+}
+
+// DeleteGameState removes the checkpoint and the lease of a finished game, the token counter is kept
+func (r *MemoryGameStateRepository) DeleteGameState(roomID string, token int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token < r.tokens[roomID] {
+		return ErrStaleLeader
+	}
+	delete(r.checkpoints, roomID)
+	delete(r.leases, roomID)
+	return nil
+}
+
+func (r *MemoryGameStateRepository) GetLeader(roomID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lease, ok := r.currentLease(roomID)
+	if !ok {
+		return "", nil
+	}
+	return lease.owner, nil
+}
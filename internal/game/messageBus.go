@@ -0,0 +1,377 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/thesrcielos/TopTankBattle/pkg/db"
+)
+
+// MessageHandler receives the payloads published on a topic
+type MessageHandler func(payload string)
+
+// MessageBus delivers room and game events between the instances of the server
+type MessageBus interface {
+	Publish(topic string, payload string) error
+	Subscribe(topic string, handler MessageHandler) error
+	Unsubscribe(topic string) error
+	SetRecoveryHandler(handler func())
+	Health() BusHealth
+	PruneGroups(alive map[string]bool) (int, error)
+}
+
+// BusHealth is the state of the connection of a MessageBus
+type BusHealth struct {
+	Connected bool      `json:"connected"`
+	LastError string    `json:"lastError,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// streamMaxLen bounds every room stream, old events are trimmed once it is reached
+const streamMaxLen = 1000
+
+// streamBlock is how long a read waits for new events before picking up new subscriptions
+const streamBlock = 1 * time.Second
+
+// streamRetryDelay is the pause after a failed read, doubled on every consecutive failure
+const streamRetryDelay = 1 * time.Second
+
+// streamMaxRetryDelay caps the backoff between reads while Redis is unreachable
+const streamMaxRetryDelay = 30 * time.Second
+
+// groupRetention is how long the consumer group of an instance that is gone is kept, so an
+// instance restarted with the same INSTANCE_ID still gets the events it missed
+const groupRetention = 5 * time.Minute
+
+// RedisMessageBus is a MessageBus backed by one Redis Stream per topic. Every instance reads
+// through its own consumer group, named after its INSTANCE_ID, so Redis keeps the position of
+// each instance and the events published while it was disconnected or restarting are replayed
+// when it comes back.
+type RedisMessageBus struct {
+	db       *redis.Client
+	handlers map[string]MessageHandler
+	running  bool
+	health   BusHealth
+	recover  func()
+	mu       sync.Mutex
+}
+
+func NewRedisMessageBus(db *redis.Client) *RedisMessageBus {
+	return &RedisMessageBus{
+		db:       db,
+		handlers: make(map[string]MessageHandler),
+		health:   BusHealth{LastError: "not connected", Since: time.Now()},
+	}
+}
+
+// SetRecoveryHandler registers the function called after the bus reconnects, to resync the
+// state that may have been missed while it was down
+func (b *RedisMessageBus) SetRecoveryHandler(handler func()) {
+	b.mu.Lock()
+	b.recover = handler
+	b.mu.Unlock()
+}
+
+func (b *RedisMessageBus) Health() BusHealth {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.health
+}
+
+// setHealth records a change of the connection state and returns if it was connected before
+func (b *RedisMessageBus) setHealth(err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	wasConnected := b.health.Connected
+	if err == nil {
+		if !wasConnected {
+			b.health = BusHealth{Connected: true, Since: time.Now()}
+		}
+		return wasConnected
+	}
+	if wasConnected {
+		b.health.Since = time.Now()
+	}
+	b.health.Connected = false
+	b.health.LastError = err.Error()
+	return wasConnected
+}
+
+func (b *RedisMessageBus) Publish(topic string, payload string) error {
+	err := b.db.XAdd(ctx, &redis.XAddArgs{
+		Stream: topic,
+		MaxLen: streamMaxLen,
+		Approx: true,
+		Values: map[string]interface{}{"payload": payload},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("error publishing to %s: %w", topic, err)
+	}
+	return nil
+}
+
+// Connect checks the connection, replays the events pending for the topics registered so
+// far and starts reading the streams
+func (b *RedisMessageBus) Connect() error {
+	if err := b.db.Ping(ctx).Err(); err != nil {
+		b.setHealth(err)
+		return fmt.Errorf("error subscribing %w", err)
+	}
+	b.setHealth(nil)
+
+	b.mu.Lock()
+	if b.running {
+		b.mu.Unlock()
+		return nil
+	}
+	b.running = true
+	topics := b.topics()
+	b.mu.Unlock()
+
+	for _, topic := range topics {
+		if err := b.join(topic); err != nil {
+			log.Println("Error joining stream:", err)
+		}
+	}
+
+	log.Printf("Subscribed to room messages")
+	go b.readLoop()
+	return nil
+}
+
+// Subscribe registers the handler of a topic and starts reading its stream from now on
+func (b *RedisMessageBus) Subscribe(topic string, handler MessageHandler) error {
+	b.mu.Lock()
+	b.handlers[topic] = handler
+	running := b.running
+	b.mu.Unlock()
+
+	if !running {
+		return nil
+	}
+	return b.join(topic)
+}
+
+// Unsubscribe removes the handler and the consumer group of this instance from the stream
+func (b *RedisMessageBus) Unsubscribe(topic string) error {
+	b.mu.Lock()
+	delete(b.handlers, topic)
+	running := b.running
+	b.mu.Unlock()
+
+	if !running {
+		return nil
+	}
+	if err := b.db.XGroupDestroy(ctx, topic, instanceID).Err(); err != nil && err != redis.Nil {
+		return fmt.Errorf("error unsubscribing from %s: %w", topic, err)
+	}
+	return nil
+}
+
+// topics returns the subscribed topics. The caller must hold mu.
+func (b *RedisMessageBus) topics() []string {
+	topics := make([]string, 0, len(b.handlers))
+	for topic := range b.handlers {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// join creates the consumer group of this instance if needed and replays the events that
+// were delivered to it but never acknowledged
+func (b *RedisMessageBus) join(topic string) error {
+	err := b.db.XGroupCreateMkStream(ctx, topic, instanceID, "$").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("error subscribing to %s: %w", topic, err)
+	}
+	for {
+		replayed, err := b.read([]string{topic, "0"}, 0)
+		if err != nil || replayed == 0 {
+			return err
+		}
+	}
+}
+
+// readLoop reads the streams for as long as the server runs. Failed reads mark the bus as
+// unhealthy and are retried with an exponential backoff, the first successful read after a
+// failure triggers the recovery handler.
+func (b *RedisMessageBus) readLoop() {
+	failures := 0
+	for {
+		b.mu.Lock()
+		topics := b.topics()
+		b.mu.Unlock()
+
+		var err error
+		if len(topics) == 0 {
+			err = b.db.Ping(ctx).Err()
+			if err == nil {
+				time.Sleep(streamBlock)
+			}
+		} else {
+			streams := make([]string, 0, len(topics)*2)
+			streams = append(streams, topics...)
+			for range topics {
+				streams = append(streams, ">")
+			}
+			_, err = b.read(streams, streamBlock)
+		}
+
+		if err == nil {
+			if !b.setHealth(nil) {
+				log.Println("Room streams recovered after", failures, "failed reads")
+				failures = 0
+				b.recovered()
+			}
+			continue
+		}
+
+		log.Println("Error reading room streams:", err)
+		if strings.HasPrefix(err.Error(), "NOGROUP") {
+			b.rejoin()
+			continue
+		}
+		b.setHealth(err)
+		failures++
+		time.Sleep(retryDelay(failures))
+	}
+}
+
+// retryDelay is the backoff after a number of consecutive failed reads
+func retryDelay(failures int) time.Duration {
+	delay := streamRetryDelay
+	for i := 1; i < failures && delay < streamMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > streamMaxRetryDelay {
+		return streamMaxRetryDelay
+	}
+	return delay
+}
+
+// rejoin recreates the consumer groups of the subscribed topics, needed when a stream was deleted
+func (b *RedisMessageBus) rejoin() {
+	b.mu.Lock()
+	topics := b.topics()
+	b.mu.Unlock()
+	for _, topic := range topics {
+		if err := b.join(topic); err != nil {
+			log.Println("Error joining stream:", err)
+		}
+	}
+}
+
+// recovered replays the pending events and runs the recovery handler
+func (b *RedisMessageBus) recovered() {
+	b.rejoin()
+
+	b.mu.Lock()
+	handler := b.recover
+	b.mu.Unlock()
+	if handler != nil {
+		go handler()
+	}
+}
+
+// read fetches the events of the streams for this instance, hands them to their handlers
+// and acknowledges them. A zero block returns immediately. It returns the number of events read.
+func (b *RedisMessageBus) read(streams []string, block time.Duration) (int, error) {
+	args := &redis.XReadGroupArgs{
+		Group:    instanceID,
+		Consumer: instanceID,
+		Streams:  streams,
+		Count:    100,
+		Block:    block,
+	}
+	if block == 0 {
+		args.Block = -1
+	}
+
+	res, err := b.db.XReadGroup(ctx, args).Result()
+	if err == redis.Nil {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	read := 0
+	for _, stream := range res {
+		b.mu.Lock()
+		handler := b.handlers[stream.Stream]
+		b.mu.Unlock()
+
+		ids := make([]string, 0, len(stream.Messages))
+		for _, msg := range stream.Messages {
+			read++
+			if payload, ok := msg.Values["payload"].(string); ok && handler != nil {
+				dispatch(handler, payload)
+			}
+			ids = append(ids, msg.ID)
+		}
+		if len(ids) == 0 {
+			continue
+		}
+		if err := b.db.XAck(ctx, stream.Stream, instanceID, ids...).Err(); err != nil {
+			log.Println("Error acknowledging room events:", err)
+		}
+	}
+	return read, nil
+}
+
+// PruneGroups destroys the consumer groups of the instances that are not alive and have not
+// read their streams for groupRetention, so the streams stop keeping pending events for them
+func (b *RedisMessageBus) PruneGroups(alive map[string]bool) (int, error) {
+	pruned := 0
+	iter := b.db.ScanType(ctx, 0, db.KeyPrefix+"*", 100, "stream").Iterator()
+	for iter.Next(ctx) {
+		stream := iter.Val()
+		groups, err := b.db.XInfoGroups(ctx, stream).Result()
+		if err != nil {
+			log.Println("Error listing consumer groups:", err)
+			continue
+		}
+		for _, group := range groups {
+			if alive[group.Name] || !b.groupIdle(stream, group.Name) {
+				continue
+			}
+			if err := b.db.XGroupDestroy(ctx, stream, group.Name).Err(); err != nil {
+				log.Println("Error destroying consumer group:", err)
+				continue
+			}
+			pruned++
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return pruned, fmt.Errorf("error listing streams: %w", err)
+	}
+	return pruned, nil
+}
+
+// groupIdle reports if no consumer of a group read the stream for groupRetention
+func (b *RedisMessageBus) groupIdle(stream string, group string) bool {
+	consumers, err := b.db.XInfoConsumers(ctx, stream, group).Result()
+	if err != nil {
+		return false
+	}
+	for _, consumer := range consumers {
+		if consumer.Idle < groupRetention {
+			return false
+		}
+	}
+	return true
+}
+
+// dispatch runs a handler without letting a panic stop the subscriber
+func dispatch(handler MessageHandler, payload string) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("Recovered from panic handling room event:", r)
+		}
+	}()
+	handler(payload)
+}
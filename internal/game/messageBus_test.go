@@ -0,0 +1,21 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelayBacksOffUpToMax(t *testing.T) {
+	assert.Equal(t, 1*time.Second, retryDelay(1))
+	assert.Equal(t, 2*time.Second, retryDelay(2))
+	assert.Equal(t, 8*time.Second, retryDelay(4))
+	assert.Equal(t, streamMaxRetryDelay, retryDelay(10))
+}
+
+func TestDispatchRecoversFromPanic(t *testing.T) {
+	assert.NotPanics(t, func() {
+		dispatch(func(payload string) { panic(payload) }, "boom")
+	})
+}
@@ -11,6 +11,152 @@ import (
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
 )
 
+// NewMockChatRepository creates a new instance of MockChatRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockChatRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockChatRepository {
+	mock := &MockChatRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockChatRepository is an autogenerated mock type for the ChatRepository type
+type MockChatRepository struct {
+	mock.Mock
+}
+
+type MockChatRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockChatRepository) EXPECT() *MockChatRepository_Expecter {
+	return &MockChatRepository_Expecter{mock: &_m.Mock}
+}
+
+// AppendMessage provides a mock function for the type MockChatRepository
+func (_mock *MockChatRepository) AppendMessage(roomId string, message ChatMessage) error {
+	ret := _mock.Called(roomId, message)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AppendMessage")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, ChatMessage) error); ok {
+		r0 = returnFunc(roomId, message)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockChatRepository_AppendMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AppendMessage'
+type MockChatRepository_AppendMessage_Call struct {
+	*mock.Call
+}
+
+// AppendMessage is a helper method to define mock.On call
+//   - roomId string
+//   - message ChatMessage
+func (_e *MockChatRepository_Expecter) AppendMessage(roomId interface{}, message interface{}) *MockChatRepository_AppendMessage_Call {
+	return &MockChatRepository_AppendMessage_Call{Call: _e.mock.On("AppendMessage", roomId, message)}
+}
+
+func (_c *MockChatRepository_AppendMessage_Call) Run(run func(roomId string, message ChatMessage)) *MockChatRepository_AppendMessage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 ChatMessage
+		if args[1] != nil {
+			arg1 = args[1].(ChatMessage)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockChatRepository_AppendMessage_Call) Return(err error) *MockChatRepository_AppendMessage_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockChatRepository_AppendMessage_Call) RunAndReturn(run func(roomId string, message ChatMessage) error) *MockChatRepository_AppendMessage_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetHistory provides a mock function for the type MockChatRepository
+func (_mock *MockChatRepository) GetHistory(roomId string) ([]ChatMessage, error) {
+	ret := _mock.Called(roomId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetHistory")
+	}
+
+	var r0 []ChatMessage
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) ([]ChatMessage, error)); ok {
+		return returnFunc(roomId)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) []ChatMessage); ok {
+		r0 = returnFunc(roomId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ChatMessage)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(roomId)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockChatRepository_GetHistory_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetHistory'
+type MockChatRepository_GetHistory_Call struct {
+	*mock.Call
+}
+
+// GetHistory is a helper method to define mock.On call
+//   - roomId string
+func (_e *MockChatRepository_Expecter) GetHistory(roomId interface{}) *MockChatRepository_GetHistory_Call {
+	return &MockChatRepository_GetHistory_Call{Call: _e.mock.On("GetHistory", roomId)}
+}
+
+func (_c *MockChatRepository_GetHistory_Call) Run(run func(roomId string)) *MockChatRepository_GetHistory_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockChatRepository_GetHistory_Call) Return(chatMessages []ChatMessage, err error) *MockChatRepository_GetHistory_Call {
+	_c.Call.Return(chatMessages, err)
+	return _c
+}
+
+func (_c *MockChatRepository_GetHistory_Call) RunAndReturn(run func(roomId string) ([]ChatMessage, error)) *MockChatRepository_GetHistory_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // NewMockLeaderElector creates a new instance of MockLeaderElector. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockLeaderElector(t interface {
@@ -78,6 +224,46 @@ func (_c *MockLeaderElector_AttemptLeadership_Call) RunAndReturn(run func(roomId
 	return _c
 }
 
+// ResyncRoom provides a mock function for the type MockLeaderElector
+func (_mock *MockLeaderElector) ResyncRoom(roomId string) {
+	_mock.Called(roomId)
+	return
+}
+
+// MockLeaderElector_ResyncRoom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResyncRoom'
+type MockLeaderElector_ResyncRoom_Call struct {
+	*mock.Call
+}
+
+// ResyncRoom is a helper method to define mock.On call
+//   - roomId string
+func (_e *MockLeaderElector_Expecter) ResyncRoom(roomId interface{}) *MockLeaderElector_ResyncRoom_Call {
+	return &MockLeaderElector_ResyncRoom_Call{Call: _e.mock.On("ResyncRoom", roomId)}
+}
+
+func (_c *MockLeaderElector_ResyncRoom_Call) Run(run func(roomId string)) *MockLeaderElector_ResyncRoom_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockLeaderElector_ResyncRoom_Call) Return() *MockLeaderElector_ResyncRoom_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockLeaderElector_ResyncRoom_Call) RunAndReturn(run func(roomId string)) *MockLeaderElector_ResyncRoom_Call {
+	_c.Run(run)
+	return _c
+}
+
 // NewMockGameService creates a new instance of MockGameService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockGameService(t interface {
@@ -452,6 +638,98 @@ func (_c *MockGameService_NotifyGameStart_Call) RunAndReturn(run func(game *stat
 	return _c
 }
 
+// NotifyPlayerConnection provides a mock function for the type MockGameService
+func (_mock *MockGameService) NotifyPlayerConnection(playerId string, connected bool) {
+	_mock.Called(playerId, connected)
+	return
+}
+
+// MockGameService_NotifyPlayerConnection_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'NotifyPlayerConnection'
+type MockGameService_NotifyPlayerConnection_Call struct {
+	*mock.Call
+}
+
+// NotifyPlayerConnection is a helper method to define mock.On call
+//   - playerId string
+//   - connected bool
+func (_e *MockGameService_Expecter) NotifyPlayerConnection(playerId interface{}, connected interface{}) *MockGameService_NotifyPlayerConnection_Call {
+	return &MockGameService_NotifyPlayerConnection_Call{Call: _e.mock.On("NotifyPlayerConnection", playerId, connected)}
+}
+
+func (_c *MockGameService_NotifyPlayerConnection_Call) Run(run func(playerId string, connected bool)) *MockGameService_NotifyPlayerConnection_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 bool
+		if args[1] != nil {
+			arg1 = args[1].(bool)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameService_NotifyPlayerConnection_Call) Return() *MockGameService_NotifyPlayerConnection_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockGameService_NotifyPlayerConnection_Call) RunAndReturn(run func(playerId string, connected bool)) *MockGameService_NotifyPlayerConnection_Call {
+	_c.Run(run)
+	return _c
+}
+
+// ReportLatency provides a mock function for the type MockGameService
+func (_mock *MockGameService) ReportLatency(playerId string, rtt time.Duration) {
+	_mock.Called(playerId, rtt)
+	return
+}
+
+// MockGameService_ReportLatency_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReportLatency'
+type MockGameService_ReportLatency_Call struct {
+	*mock.Call
+}
+
+// ReportLatency is a helper method to define mock.On call
+//   - playerId string
+//   - rtt time.Duration
+func (_e *MockGameService_Expecter) ReportLatency(playerId interface{}, rtt interface{}) *MockGameService_ReportLatency_Call {
+	return &MockGameService_ReportLatency_Call{Call: _e.mock.On("ReportLatency", playerId, rtt)}
+}
+
+func (_c *MockGameService_ReportLatency_Call) Run(run func(playerId string, rtt time.Duration)) *MockGameService_ReportLatency_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 time.Duration
+		if args[1] != nil {
+			arg1 = args[1].(time.Duration)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameService_ReportLatency_Call) Return() *MockGameService_ReportLatency_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockGameService_ReportLatency_Call) RunAndReturn(run func(playerId string, rtt time.Duration)) *MockGameService_ReportLatency_Call {
+	_c.Run(run)
+	return _c
+}
+
 // RevivePlayer provides a mock function for the type MockGameService
 func (_mock *MockGameService) RevivePlayer(playerId string, gameState *state.GameState) {
 	_mock.Called(playerId, gameState)
@@ -681,6 +959,76 @@ func (_c *MockGameService_ShootBullet_Call) RunAndReturn(run func(bullet *state.
 	return _c
 }
 
+// Snapshot provides a mock function for the type MockGameService
+func (_mock *MockGameService) Snapshot(roomId string) (*Room, *state.GameState, error) {
+	ret := _mock.Called(roomId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Snapshot")
+	}
+
+	var r0 *Room
+	var r1 *state.GameState
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(string) (*Room, *state.GameState, error)); ok {
+		return returnFunc(roomId)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) *Room); ok {
+		r0 = returnFunc(roomId)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*Room)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) *state.GameState); ok {
+		r1 = returnFunc(roomId)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*state.GameState)
+		}
+	}
+	if returnFunc, ok := ret.Get(2).(func(string) error); ok {
+		r2 = returnFunc(roomId)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockGameService_Snapshot_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Snapshot'
+type MockGameService_Snapshot_Call struct {
+	*mock.Call
+}
+
+// Snapshot is a helper method to define mock.On call
+//   - roomId string
+func (_e *MockGameService_Expecter) Snapshot(roomId interface{}) *MockGameService_Snapshot_Call {
+	return &MockGameService_Snapshot_Call{Call: _e.mock.On("Snapshot", roomId)}
+}
+
+func (_c *MockGameService_Snapshot_Call) Run(run func(roomId string)) *MockGameService_Snapshot_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameService_Snapshot_Call) Return(room *Room, gameState *state.GameState, err error) *MockGameService_Snapshot_Call {
+	_c.Call.Return(room, gameState, err)
+	return _c
+}
+
+func (_c *MockGameService_Snapshot_Call) RunAndReturn(run func(roomId string) (*Room, *state.GameState, error)) *MockGameService_Snapshot_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // StartGame provides a mock function for the type MockGameService
 func (_mock *MockGameService) StartGame(playerId string, roomId string, test bool) error {
 	ret := _mock.Called(playerId, roomId, test)
@@ -1373,13 +1721,13 @@ func (_c *MockGameService_rectCollision_Call) RunAndReturn(run func(point state.
 	return _c
 }
 
-// NewMockGameStateRepository creates a new instance of MockGameStateRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// NewMockMessageBus creates a new instance of MockMessageBus. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
-func NewMockGameStateRepository(t interface {
+func NewMockMessageBus(t interface {
 	mock.TestingT
 	Cleanup(func())
-}) *MockGameStateRepository {
-	mock := &MockGameStateRepository{}
+}) *MockMessageBus {
+	mock := &MockMessageBus{}
 	mock.Mock.Test(t)
 
 	t.Cleanup(func() { mock.AssertExpectations(t) })
@@ -1387,41 +1735,862 @@ func NewMockGameStateRepository(t interface {
 	return mock
 }
 
-// MockGameStateRepository is an autogenerated mock type for the GameStateRepository type
-type MockGameStateRepository struct {
+// MockMessageBus is an autogenerated mock type for the MessageBus type
+type MockMessageBus struct {
 	mock.Mock
 }
 
-type MockGameStateRepository_Expecter struct {
+type MockMessageBus_Expecter struct {
 	mock *mock.Mock
 }
 
-func (_m *MockGameStateRepository) EXPECT() *MockGameStateRepository_Expecter {
-	return &MockGameStateRepository_Expecter{mock: &_m.Mock}
+func (_m *MockMessageBus) EXPECT() *MockMessageBus_Expecter {
+	return &MockMessageBus_Expecter{mock: &_m.Mock}
 }
 
-// PublishToRoom provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) PublishToRoom(payload string) {
-	_mock.Called(payload)
-	return
+// Health provides a mock function for the type MockMessageBus
+func (_mock *MockMessageBus) Health() BusHealth {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for Health")
+	}
+
+	var r0 BusHealth
+	if returnFunc, ok := ret.Get(0).(func() BusHealth); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(BusHealth)
+	}
+	return r0
+}
+
+// MockMessageBus_Health_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Health'
+type MockMessageBus_Health_Call struct {
+	*mock.Call
+}
+
+// Health is a helper method to define mock.On call
+func (_e *MockMessageBus_Expecter) Health() *MockMessageBus_Health_Call {
+	return &MockMessageBus_Health_Call{Call: _e.mock.On("Health")}
+}
+
+func (_c *MockMessageBus_Health_Call) Run(run func()) *MockMessageBus_Health_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockMessageBus_Health_Call) Return(busHealth BusHealth) *MockMessageBus_Health_Call {
+	_c.Call.Return(busHealth)
+	return _c
+}
+
+func (_c *MockMessageBus_Health_Call) RunAndReturn(run func() BusHealth) *MockMessageBus_Health_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PruneGroups provides a mock function for the type MockMessageBus
+func (_mock *MockMessageBus) PruneGroups(alive map[string]bool) (int, error) {
+	ret := _mock.Called(alive)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PruneGroups")
+	}
+
+	var r0 int
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(map[string]bool) (int, error)); ok {
+		return returnFunc(alive)
+	}
+	if returnFunc, ok := ret.Get(0).(func(map[string]bool) int); ok {
+		r0 = returnFunc(alive)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+	if returnFunc, ok := ret.Get(1).(func(map[string]bool) error); ok {
+		r1 = returnFunc(alive)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockMessageBus_PruneGroups_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PruneGroups'
+type MockMessageBus_PruneGroups_Call struct {
+	*mock.Call
+}
+
+// PruneGroups is a helper method to define mock.On call
+//   - alive map[string]bool
+func (_e *MockMessageBus_Expecter) PruneGroups(alive interface{}) *MockMessageBus_PruneGroups_Call {
+	return &MockMessageBus_PruneGroups_Call{Call: _e.mock.On("PruneGroups", alive)}
+}
+
+func (_c *MockMessageBus_PruneGroups_Call) Run(run func(alive map[string]bool)) *MockMessageBus_PruneGroups_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 map[string]bool
+		if args[0] != nil {
+			arg0 = args[0].(map[string]bool)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockMessageBus_PruneGroups_Call) Return(n int, err error) *MockMessageBus_PruneGroups_Call {
+	_c.Call.Return(n, err)
+	return _c
+}
+
+func (_c *MockMessageBus_PruneGroups_Call) RunAndReturn(run func(alive map[string]bool) (int, error)) *MockMessageBus_PruneGroups_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Publish provides a mock function for the type MockMessageBus
+func (_mock *MockMessageBus) Publish(topic string, payload string) error {
+	ret := _mock.Called(topic, payload)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Publish")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = returnFunc(topic, payload)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockMessageBus_Publish_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Publish'
+type MockMessageBus_Publish_Call struct {
+	*mock.Call
+}
+
+// Publish is a helper method to define mock.On call
+//   - topic string
+//   - payload string
+func (_e *MockMessageBus_Expecter) Publish(topic interface{}, payload interface{}) *MockMessageBus_Publish_Call {
+	return &MockMessageBus_Publish_Call{Call: _e.mock.On("Publish", topic, payload)}
+}
+
+func (_c *MockMessageBus_Publish_Call) Run(run func(topic string, payload string)) *MockMessageBus_Publish_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockMessageBus_Publish_Call) Return(err error) *MockMessageBus_Publish_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockMessageBus_Publish_Call) RunAndReturn(run func(topic string, payload string) error) *MockMessageBus_Publish_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetRecoveryHandler provides a mock function for the type MockMessageBus
+func (_mock *MockMessageBus) SetRecoveryHandler(handler func()) {
+	_mock.Called(handler)
+	return
+}
+
+// MockMessageBus_SetRecoveryHandler_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetRecoveryHandler'
+type MockMessageBus_SetRecoveryHandler_Call struct {
+	*mock.Call
+}
+
+// SetRecoveryHandler is a helper method to define mock.On call
+//   - handler func()
+func (_e *MockMessageBus_Expecter) SetRecoveryHandler(handler interface{}) *MockMessageBus_SetRecoveryHandler_Call {
+	return &MockMessageBus_SetRecoveryHandler_Call{Call: _e.mock.On("SetRecoveryHandler", handler)}
+}
+
+func (_c *MockMessageBus_SetRecoveryHandler_Call) Run(run func(handler func())) *MockMessageBus_SetRecoveryHandler_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 func()
+		if args[0] != nil {
+			arg0 = args[0].(func())
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockMessageBus_SetRecoveryHandler_Call) Return() *MockMessageBus_SetRecoveryHandler_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockMessageBus_SetRecoveryHandler_Call) RunAndReturn(run func(handler func())) *MockMessageBus_SetRecoveryHandler_Call {
+	_c.Run(run)
+	return _c
+}
+
+// Subscribe provides a mock function for the type MockMessageBus
+func (_mock *MockMessageBus) Subscribe(topic string, handler MessageHandler) error {
+	ret := _mock.Called(topic, handler)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Subscribe")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, MessageHandler) error); ok {
+		r0 = returnFunc(topic, handler)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockMessageBus_Subscribe_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Subscribe'
+type MockMessageBus_Subscribe_Call struct {
+	*mock.Call
+}
+
+// Subscribe is a helper method to define mock.On call
+//   - topic string
+//   - handler MessageHandler
+func (_e *MockMessageBus_Expecter) Subscribe(topic interface{}, handler interface{}) *MockMessageBus_Subscribe_Call {
+	return &MockMessageBus_Subscribe_Call{Call: _e.mock.On("Subscribe", topic, handler)}
+}
+
+func (_c *MockMessageBus_Subscribe_Call) Run(run func(topic string, handler MessageHandler)) *MockMessageBus_Subscribe_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 MessageHandler
+		if args[1] != nil {
+			arg1 = args[1].(MessageHandler)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockMessageBus_Subscribe_Call) Return(err error) *MockMessageBus_Subscribe_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockMessageBus_Subscribe_Call) RunAndReturn(run func(topic string, handler MessageHandler) error) *MockMessageBus_Subscribe_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Unsubscribe provides a mock function for the type MockMessageBus
+func (_mock *MockMessageBus) Unsubscribe(topic string) error {
+	ret := _mock.Called(topic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Unsubscribe")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string) error); ok {
+		r0 = returnFunc(topic)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockMessageBus_Unsubscribe_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Unsubscribe'
+type MockMessageBus_Unsubscribe_Call struct {
+	*mock.Call
+}
+
+// Unsubscribe is a helper method to define mock.On call
+//   - topic string
+func (_e *MockMessageBus_Expecter) Unsubscribe(topic interface{}) *MockMessageBus_Unsubscribe_Call {
+	return &MockMessageBus_Unsubscribe_Call{Call: _e.mock.On("Unsubscribe", topic)}
+}
+
+func (_c *MockMessageBus_Unsubscribe_Call) Run(run func(topic string)) *MockMessageBus_Unsubscribe_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockMessageBus_Unsubscribe_Call) Return(err error) *MockMessageBus_Unsubscribe_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockMessageBus_Unsubscribe_Call) RunAndReturn(run func(topic string) error) *MockMessageBus_Unsubscribe_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// NewMockGameStateRepository creates a new instance of MockGameStateRepository. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockGameStateRepository(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockGameStateRepository {
+	mock := &MockGameStateRepository{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockGameStateRepository is an autogenerated mock type for the GameStateRepository type
+type MockGameStateRepository struct {
+	mock.Mock
+}
+
+type MockGameStateRepository_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockGameStateRepository) EXPECT() *MockGameStateRepository_Expecter {
+	return &MockGameStateRepository_Expecter{mock: &_m.Mock}
+}
+
+// DeleteGameState provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) DeleteGameState(roomID string, token int64) error {
+	ret := _mock.Called(roomID, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteGameState")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = returnFunc(roomID, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockGameStateRepository_DeleteGameState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteGameState'
+type MockGameStateRepository_DeleteGameState_Call struct {
+	*mock.Call
+}
+
+// DeleteGameState is a helper method to define mock.On call
+//   - roomID string
+//   - token int64
+func (_e *MockGameStateRepository_Expecter) DeleteGameState(roomID interface{}, token interface{}) *MockGameStateRepository_DeleteGameState_Call {
+	return &MockGameStateRepository_DeleteGameState_Call{Call: _e.mock.On("DeleteGameState", roomID, token)}
+}
+
+func (_c *MockGameStateRepository_DeleteGameState_Call) Run(run func(roomID string, token int64)) *MockGameStateRepository_DeleteGameState_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 int64
+		if args[1] != nil {
+			arg1 = args[1].(int64)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_DeleteGameState_Call) Return(err error) *MockGameStateRepository_DeleteGameState_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockGameStateRepository_DeleteGameState_Call) RunAndReturn(run func(roomID string, token int64) error) *MockGameStateRepository_DeleteGameState_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetLeader provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) GetLeader(roomID string) (string, error) {
+	ret := _mock.Called(roomID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetLeader")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return returnFunc(roomID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) string); ok {
+		r0 = returnFunc(roomID)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(roomID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockGameStateRepository_GetLeader_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetLeader'
+type MockGameStateRepository_GetLeader_Call struct {
+	*mock.Call
+}
+
+// GetLeader is a helper method to define mock.On call
+//   - roomID string
+func (_e *MockGameStateRepository_Expecter) GetLeader(roomID interface{}) *MockGameStateRepository_GetLeader_Call {
+	return &MockGameStateRepository_GetLeader_Call{Call: _e.mock.On("GetLeader", roomID)}
+}
+
+func (_c *MockGameStateRepository_GetLeader_Call) Run(run func(roomID string)) *MockGameStateRepository_GetLeader_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_GetLeader_Call) Return(s string, err error) *MockGameStateRepository_GetLeader_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MockGameStateRepository_GetLeader_Call) RunAndReturn(run func(roomID string) (string, error)) *MockGameStateRepository_GetLeader_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// PublishControl provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) PublishControl(payload string) {
+	_mock.Called(payload)
+	return
+}
+
+// MockGameStateRepository_PublishControl_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishControl'
+type MockGameStateRepository_PublishControl_Call struct {
+	*mock.Call
+}
+
+// PublishControl is a helper method to define mock.On call
+//   - payload string
+func (_e *MockGameStateRepository_Expecter) PublishControl(payload interface{}) *MockGameStateRepository_PublishControl_Call {
+	return &MockGameStateRepository_PublishControl_Call{Call: _e.mock.On("PublishControl", payload)}
+}
+
+func (_c *MockGameStateRepository_PublishControl_Call) Run(run func(payload string)) *MockGameStateRepository_PublishControl_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_PublishControl_Call) Return() *MockGameStateRepository_PublishControl_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockGameStateRepository_PublishControl_Call) RunAndReturn(run func(payload string)) *MockGameStateRepository_PublishControl_Call {
+	_c.Run(run)
+	return _c
+}
+
+// PublishToRoom provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) PublishToRoom(roomID string, payload string) {
+	_mock.Called(roomID, payload)
+	return
+}
+
+// MockGameStateRepository_PublishToRoom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishToRoom'
+type MockGameStateRepository_PublishToRoom_Call struct {
+	*mock.Call
+}
+
+// PublishToRoom is a helper method to define mock.On call
+//   - roomID string
+//   - payload string
+func (_e *MockGameStateRepository_Expecter) PublishToRoom(roomID interface{}, payload interface{}) *MockGameStateRepository_PublishToRoom_Call {
+	return &MockGameStateRepository_PublishToRoom_Call{Call: _e.mock.On("PublishToRoom", roomID, payload)}
+}
+
+func (_c *MockGameStateRepository_PublishToRoom_Call) Run(run func(roomID string, payload string)) *MockGameStateRepository_PublishToRoom_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_PublishToRoom_Call) Return() *MockGameStateRepository_PublishToRoom_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockGameStateRepository_PublishToRoom_Call) RunAndReturn(run func(roomID string, payload string)) *MockGameStateRepository_PublishToRoom_Call {
+	_c.Run(run)
+	return _c
+}
+
+// ReleaseLeadership provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) ReleaseLeadership(roomID string, token int64) error {
+	ret := _mock.Called(roomID, token)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseLeadership")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string, int64) error); ok {
+		r0 = returnFunc(roomID, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockGameStateRepository_ReleaseLeadership_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ReleaseLeadership'
+type MockGameStateRepository_ReleaseLeadership_Call struct {
+	*mock.Call
+}
+
+// ReleaseLeadership is a helper method to define mock.On call
+//   - roomID string
+//   - token int64
+func (_e *MockGameStateRepository_Expecter) ReleaseLeadership(roomID interface{}, token interface{}) *MockGameStateRepository_ReleaseLeadership_Call {
+	return &MockGameStateRepository_ReleaseLeadership_Call{Call: _e.mock.On("ReleaseLeadership", roomID, token)}
+}
+
+func (_c *MockGameStateRepository_ReleaseLeadership_Call) Run(run func(roomID string, token int64)) *MockGameStateRepository_ReleaseLeadership_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 int64
+		if args[1] != nil {
+			arg1 = args[1].(int64)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_ReleaseLeadership_Call) Return(err error) *MockGameStateRepository_ReleaseLeadership_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockGameStateRepository_ReleaseLeadership_Call) RunAndReturn(run func(roomID string, token int64) error) *MockGameStateRepository_ReleaseLeadership_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RenewLeadership provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) RenewLeadership(roomID string, token int64, expiration time.Duration) (bool, error) {
+	ret := _mock.Called(roomID, token, expiration)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RenewLeadership")
+	}
+
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string, int64, time.Duration) (bool, error)); ok {
+		return returnFunc(roomID, token, expiration)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string, int64, time.Duration) bool); ok {
+		r0 = returnFunc(roomID, token, expiration)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string, int64, time.Duration) error); ok {
+		r1 = returnFunc(roomID, token, expiration)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockGameStateRepository_RenewLeadership_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RenewLeadership'
+type MockGameStateRepository_RenewLeadership_Call struct {
+	*mock.Call
+}
+
+// RenewLeadership is a helper method to define mock.On call
+//   - roomID string
+//   - token int64
+//   - expiration time.Duration
+func (_e *MockGameStateRepository_Expecter) RenewLeadership(roomID interface{}, token interface{}, expiration interface{}) *MockGameStateRepository_RenewLeadership_Call {
+	return &MockGameStateRepository_RenewLeadership_Call{Call: _e.mock.On("RenewLeadership", roomID, token, expiration)}
+}
+
+func (_c *MockGameStateRepository_RenewLeadership_Call) Run(run func(roomID string, token int64, expiration time.Duration)) *MockGameStateRepository_RenewLeadership_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		var arg1 int64
+		if args[1] != nil {
+			arg1 = args[1].(int64)
+		}
+		var arg2 time.Duration
+		if args[2] != nil {
+			arg2 = args[2].(time.Duration)
+		}
+		run(
+			arg0,
+			arg1,
+			arg2,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_RenewLeadership_Call) Return(b bool, err error) *MockGameStateRepository_RenewLeadership_Call {
+	_c.Call.Return(b, err)
+	return _c
+}
+
+func (_c *MockGameStateRepository_RenewLeadership_Call) RunAndReturn(run func(roomID string, token int64, expiration time.Duration) (bool, error)) *MockGameStateRepository_RenewLeadership_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// RestoreGameState provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) RestoreGameState(roomID string) (*state.GameState, error) {
+	ret := _mock.Called(roomID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreGameState")
+	}
+
+	var r0 *state.GameState
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) (*state.GameState, error)); ok {
+		return returnFunc(roomID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) *state.GameState); ok {
+		r0 = returnFunc(roomID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*state.GameState)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(roomID)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockGameStateRepository_RestoreGameState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreGameState'
+type MockGameStateRepository_RestoreGameState_Call struct {
+	*mock.Call
+}
+
+// RestoreGameState is a helper method to define mock.On call
+//   - roomID string
+func (_e *MockGameStateRepository_Expecter) RestoreGameState(roomID interface{}) *MockGameStateRepository_RestoreGameState_Call {
+	return &MockGameStateRepository_RestoreGameState_Call{Call: _e.mock.On("RestoreGameState", roomID)}
+}
+
+func (_c *MockGameStateRepository_RestoreGameState_Call) Run(run func(roomID string)) *MockGameStateRepository_RestoreGameState_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_RestoreGameState_Call) Return(gameState *state.GameState, err error) *MockGameStateRepository_RestoreGameState_Call {
+	_c.Call.Return(gameState, err)
+	return _c
+}
+
+func (_c *MockGameStateRepository_RestoreGameState_Call) RunAndReturn(run func(roomID string) (*state.GameState, error)) *MockGameStateRepository_RestoreGameState_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SaveGameState provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) SaveGameState(gameState *state.GameState) error {
+	ret := _mock.Called(gameState)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveGameState")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(*state.GameState) error); ok {
+		r0 = returnFunc(gameState)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockGameStateRepository_SaveGameState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveGameState'
+type MockGameStateRepository_SaveGameState_Call struct {
+	*mock.Call
+}
+
+// SaveGameState is a helper method to define mock.On call
+//   - gameState *state.GameState
+func (_e *MockGameStateRepository_Expecter) SaveGameState(gameState interface{}) *MockGameStateRepository_SaveGameState_Call {
+	return &MockGameStateRepository_SaveGameState_Call{Call: _e.mock.On("SaveGameState", gameState)}
+}
+
+func (_c *MockGameStateRepository_SaveGameState_Call) Run(run func(gameState *state.GameState)) *MockGameStateRepository_SaveGameState_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *state.GameState
+		if args[0] != nil {
+			arg0 = args[0].(*state.GameState)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_SaveGameState_Call) Return(err error) *MockGameStateRepository_SaveGameState_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockGameStateRepository_SaveGameState_Call) RunAndReturn(run func(gameState *state.GameState) error) *MockGameStateRepository_SaveGameState_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SendReceivedMessage provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) SendReceivedMessage(messageEncoded string) {
+	_mock.Called(messageEncoded)
+	return
+}
+
+// MockGameStateRepository_SendReceivedMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendReceivedMessage'
+type MockGameStateRepository_SendReceivedMessage_Call struct {
+	*mock.Call
+}
+
+// SendReceivedMessage is a helper method to define mock.On call
+//   - messageEncoded string
+func (_e *MockGameStateRepository_Expecter) SendReceivedMessage(messageEncoded interface{}) *MockGameStateRepository_SendReceivedMessage_Call {
+	return &MockGameStateRepository_SendReceivedMessage_Call{Call: _e.mock.On("SendReceivedMessage", messageEncoded)}
+}
+
+func (_c *MockGameStateRepository_SendReceivedMessage_Call) Run(run func(messageEncoded string)) *MockGameStateRepository_SendReceivedMessage_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
 }
 
-// MockGameStateRepository_PublishToRoom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'PublishToRoom'
-type MockGameStateRepository_PublishToRoom_Call struct {
+func (_c *MockGameStateRepository_SendReceivedMessage_Call) Return() *MockGameStateRepository_SendReceivedMessage_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockGameStateRepository_SendReceivedMessage_Call) RunAndReturn(run func(messageEncoded string)) *MockGameStateRepository_SendReceivedMessage_Call {
+	_c.Run(run)
+	return _c
+}
+
+// SetLeaderElector provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) SetLeaderElector(elector LeaderElector) {
+	_mock.Called(elector)
+	return
+}
+
+// MockGameStateRepository_SetLeaderElector_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetLeaderElector'
+type MockGameStateRepository_SetLeaderElector_Call struct {
 	*mock.Call
 }
 
-// PublishToRoom is a helper method to define mock.On call
-//   - payload string
-func (_e *MockGameStateRepository_Expecter) PublishToRoom(payload interface{}) *MockGameStateRepository_PublishToRoom_Call {
-	return &MockGameStateRepository_PublishToRoom_Call{Call: _e.mock.On("PublishToRoom", payload)}
+// SetLeaderElector is a helper method to define mock.On call
+//   - elector LeaderElector
+func (_e *MockGameStateRepository_Expecter) SetLeaderElector(elector interface{}) *MockGameStateRepository_SetLeaderElector_Call {
+	return &MockGameStateRepository_SetLeaderElector_Call{Call: _e.mock.On("SetLeaderElector", elector)}
 }
 
-func (_c *MockGameStateRepository_PublishToRoom_Call) Run(run func(payload string)) *MockGameStateRepository_PublishToRoom_Call {
+func (_c *MockGameStateRepository_SetLeaderElector_Call) Run(run func(elector LeaderElector)) *MockGameStateRepository_SetLeaderElector_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 string
+		var arg0 LeaderElector
 		if args[0] != nil {
-			arg0 = args[0].(string)
+			arg0 = args[0].(LeaderElector)
 		}
 		run(
 			arg0,
@@ -1430,113 +2599,196 @@ func (_c *MockGameStateRepository_PublishToRoom_Call) Run(run func(payload strin
 	return _c
 }
 
-func (_c *MockGameStateRepository_PublishToRoom_Call) Return() *MockGameStateRepository_PublishToRoom_Call {
+func (_c *MockGameStateRepository_SetLeaderElector_Call) Return() *MockGameStateRepository_SetLeaderElector_Call {
 	_c.Call.Return()
 	return _c
 }
 
-func (_c *MockGameStateRepository_PublishToRoom_Call) RunAndReturn(run func(payload string)) *MockGameStateRepository_PublishToRoom_Call {
+func (_c *MockGameStateRepository_SetLeaderElector_Call) RunAndReturn(run func(elector LeaderElector)) *MockGameStateRepository_SetLeaderElector_Call {
 	_c.Run(run)
 	return _c
 }
 
-// RenewLeadership provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) RenewLeadership(roomID string, expiration time.Duration) (bool, error) {
-	ret := _mock.Called(roomID, expiration)
+// SetLeaderLocator provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) SetLeaderLocator(locator *LeaderLocator) {
+	_mock.Called(locator)
+	return
+}
+
+// MockGameStateRepository_SetLeaderLocator_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetLeaderLocator'
+type MockGameStateRepository_SetLeaderLocator_Call struct {
+	*mock.Call
+}
+
+// SetLeaderLocator is a helper method to define mock.On call
+//   - locator *LeaderLocator
+func (_e *MockGameStateRepository_Expecter) SetLeaderLocator(locator interface{}) *MockGameStateRepository_SetLeaderLocator_Call {
+	return &MockGameStateRepository_SetLeaderLocator_Call{Call: _e.mock.On("SetLeaderLocator", locator)}
+}
+
+func (_c *MockGameStateRepository_SetLeaderLocator_Call) Run(run func(locator *LeaderLocator)) *MockGameStateRepository_SetLeaderLocator_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *LeaderLocator
+		if args[0] != nil {
+			arg0 = args[0].(*LeaderLocator)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_SetLeaderLocator_Call) Return() *MockGameStateRepository_SetLeaderLocator_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockGameStateRepository_SetLeaderLocator_Call) RunAndReturn(run func(locator *LeaderLocator)) *MockGameStateRepository_SetLeaderLocator_Call {
+	_c.Run(run)
+	return _c
+}
+
+// SubscribeRoom provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) SubscribeRoom(roomID string) error {
+	ret := _mock.Called(roomID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RenewLeadership")
+		panic("no return value specified for SubscribeRoom")
 	}
 
-	var r0 bool
-	var r1 error
-	if returnFunc, ok := ret.Get(0).(func(string, time.Duration) (bool, error)); ok {
-		return returnFunc(roomID, expiration)
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string) error); ok {
+		r0 = returnFunc(roomID)
+	} else {
+		r0 = ret.Error(0)
 	}
-	if returnFunc, ok := ret.Get(0).(func(string, time.Duration) bool); ok {
-		r0 = returnFunc(roomID, expiration)
+	return r0
+}
+
+// MockGameStateRepository_SubscribeRoom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SubscribeRoom'
+type MockGameStateRepository_SubscribeRoom_Call struct {
+	*mock.Call
+}
+
+// SubscribeRoom is a helper method to define mock.On call
+//   - roomID string
+func (_e *MockGameStateRepository_Expecter) SubscribeRoom(roomID interface{}) *MockGameStateRepository_SubscribeRoom_Call {
+	return &MockGameStateRepository_SubscribeRoom_Call{Call: _e.mock.On("SubscribeRoom", roomID)}
+}
+
+func (_c *MockGameStateRepository_SubscribeRoom_Call) Run(run func(roomID string)) *MockGameStateRepository_SubscribeRoom_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockGameStateRepository_SubscribeRoom_Call) Return(err error) *MockGameStateRepository_SubscribeRoom_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockGameStateRepository_SubscribeRoom_Call) RunAndReturn(run func(roomID string) error) *MockGameStateRepository_SubscribeRoom_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// TryToBecomeLeader provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) TryToBecomeLeader(roomID string) (int64, bool) {
+	ret := _mock.Called(roomID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for TryToBecomeLeader")
+	}
+
+	var r0 int64
+	var r1 bool
+	if returnFunc, ok := ret.Get(0).(func(string) (int64, bool)); ok {
+		return returnFunc(roomID)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) int64); ok {
+		r0 = returnFunc(roomID)
 	} else {
-		r0 = ret.Get(0).(bool)
+		r0 = ret.Get(0).(int64)
 	}
-	if returnFunc, ok := ret.Get(1).(func(string, time.Duration) error); ok {
-		r1 = returnFunc(roomID, expiration)
+	if returnFunc, ok := ret.Get(1).(func(string) bool); ok {
+		r1 = returnFunc(roomID)
 	} else {
-		r1 = ret.Error(1)
+		r1 = ret.Get(1).(bool)
 	}
 	return r0, r1
 }
 
-// MockGameStateRepository_RenewLeadership_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RenewLeadership'
-type MockGameStateRepository_RenewLeadership_Call struct {
+// MockGameStateRepository_TryToBecomeLeader_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TryToBecomeLeader'
+type MockGameStateRepository_TryToBecomeLeader_Call struct {
 	*mock.Call
 }
 
-// RenewLeadership is a helper method to define mock.On call
+// TryToBecomeLeader is a helper method to define mock.On call
 //   - roomID string
-//   - expiration time.Duration
-func (_e *MockGameStateRepository_Expecter) RenewLeadership(roomID interface{}, expiration interface{}) *MockGameStateRepository_RenewLeadership_Call {
-	return &MockGameStateRepository_RenewLeadership_Call{Call: _e.mock.On("RenewLeadership", roomID, expiration)}
+func (_e *MockGameStateRepository_Expecter) TryToBecomeLeader(roomID interface{}) *MockGameStateRepository_TryToBecomeLeader_Call {
+	return &MockGameStateRepository_TryToBecomeLeader_Call{Call: _e.mock.On("TryToBecomeLeader", roomID)}
 }
 
-func (_c *MockGameStateRepository_RenewLeadership_Call) Run(run func(roomID string, expiration time.Duration)) *MockGameStateRepository_RenewLeadership_Call {
+func (_c *MockGameStateRepository_TryToBecomeLeader_Call) Run(run func(roomID string)) *MockGameStateRepository_TryToBecomeLeader_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
 			arg0 = args[0].(string)
 		}
-		var arg1 time.Duration
-		if args[1] != nil {
-			arg1 = args[1].(time.Duration)
-		}
 		run(
 			arg0,
-			arg1,
 		)
 	})
 	return _c
 }
 
-func (_c *MockGameStateRepository_RenewLeadership_Call) Return(b bool, err error) *MockGameStateRepository_RenewLeadership_Call {
-	_c.Call.Return(b, err)
+func (_c *MockGameStateRepository_TryToBecomeLeader_Call) Return(n int64, b bool) *MockGameStateRepository_TryToBecomeLeader_Call {
+	_c.Call.Return(n, b)
 	return _c
 }
 
-func (_c *MockGameStateRepository_RenewLeadership_Call) RunAndReturn(run func(roomID string, expiration time.Duration) (bool, error)) *MockGameStateRepository_RenewLeadership_Call {
+func (_c *MockGameStateRepository_TryToBecomeLeader_Call) RunAndReturn(run func(roomID string) (int64, bool)) *MockGameStateRepository_TryToBecomeLeader_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// RestoreGameState provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) RestoreGameState(roomID string) *state.GameState {
+// UnsubscribeRoom provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) UnsubscribeRoom(roomID string) error {
 	ret := _mock.Called(roomID)
 
 	if len(ret) == 0 {
-		panic("no return value specified for RestoreGameState")
+		panic("no return value specified for UnsubscribeRoom")
 	}
 
-	var r0 *state.GameState
-	if returnFunc, ok := ret.Get(0).(func(string) *state.GameState); ok {
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string) error); ok {
 		r0 = returnFunc(roomID)
 	} else {
-		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(*state.GameState)
-		}
+		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockGameStateRepository_RestoreGameState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'RestoreGameState'
-type MockGameStateRepository_RestoreGameState_Call struct {
+// MockGameStateRepository_UnsubscribeRoom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UnsubscribeRoom'
+type MockGameStateRepository_UnsubscribeRoom_Call struct {
 	*mock.Call
 }
 
-// RestoreGameState is a helper method to define mock.On call
+// UnsubscribeRoom is a helper method to define mock.On call
 //   - roomID string
-func (_e *MockGameStateRepository_Expecter) RestoreGameState(roomID interface{}) *MockGameStateRepository_RestoreGameState_Call {
-	return &MockGameStateRepository_RestoreGameState_Call{Call: _e.mock.On("RestoreGameState", roomID)}
+func (_e *MockGameStateRepository_Expecter) UnsubscribeRoom(roomID interface{}) *MockGameStateRepository_UnsubscribeRoom_Call {
+	return &MockGameStateRepository_UnsubscribeRoom_Call{Call: _e.mock.On("UnsubscribeRoom", roomID)}
 }
 
-func (_c *MockGameStateRepository_RestoreGameState_Call) Run(run func(roomID string)) *MockGameStateRepository_RestoreGameState_Call {
+func (_c *MockGameStateRepository_UnsubscribeRoom_Call) Run(run func(roomID string)) *MockGameStateRepository_UnsubscribeRoom_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
@@ -1549,118 +2801,174 @@ func (_c *MockGameStateRepository_RestoreGameState_Call) Run(run func(roomID str
 	return _c
 }
 
-func (_c *MockGameStateRepository_RestoreGameState_Call) Return(gameState *state.GameState) *MockGameStateRepository_RestoreGameState_Call {
-	_c.Call.Return(gameState)
+func (_c *MockGameStateRepository_UnsubscribeRoom_Call) Return(err error) *MockGameStateRepository_UnsubscribeRoom_Call {
+	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockGameStateRepository_RestoreGameState_Call) RunAndReturn(run func(roomID string) *state.GameState) *MockGameStateRepository_RestoreGameState_Call {
+func (_c *MockGameStateRepository_UnsubscribeRoom_Call) RunAndReturn(run func(roomID string) error) *MockGameStateRepository_UnsubscribeRoom_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// SaveGameState provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) SaveGameState(gameState *state.GameState) {
-	_mock.Called(gameState)
+// UpdateGameBullets provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) UpdateGameBullets(bullet state.Bullet, players []string) {
+	_mock.Called(bullet, players)
 	return
 }
 
-// MockGameStateRepository_SaveGameState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SaveGameState'
-type MockGameStateRepository_SaveGameState_Call struct {
+// MockGameStateRepository_UpdateGameBullets_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateGameBullets'
+type MockGameStateRepository_UpdateGameBullets_Call struct {
 	*mock.Call
 }
 
-// SaveGameState is a helper method to define mock.On call
-//   - gameState *state.GameState
-func (_e *MockGameStateRepository_Expecter) SaveGameState(gameState interface{}) *MockGameStateRepository_SaveGameState_Call {
-	return &MockGameStateRepository_SaveGameState_Call{Call: _e.mock.On("SaveGameState", gameState)}
+// UpdateGameBullets is a helper method to define mock.On call
+//   - bullet state.Bullet
+//   - players []string
+func (_e *MockGameStateRepository_Expecter) UpdateGameBullets(bullet interface{}, players interface{}) *MockGameStateRepository_UpdateGameBullets_Call {
+	return &MockGameStateRepository_UpdateGameBullets_Call{Call: _e.mock.On("UpdateGameBullets", bullet, players)}
 }
 
-func (_c *MockGameStateRepository_SaveGameState_Call) Run(run func(gameState *state.GameState)) *MockGameStateRepository_SaveGameState_Call {
+func (_c *MockGameStateRepository_UpdateGameBullets_Call) Run(run func(bullet state.Bullet, players []string)) *MockGameStateRepository_UpdateGameBullets_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 *state.GameState
+		var arg0 state.Bullet
 		if args[0] != nil {
-			arg0 = args[0].(*state.GameState)
+			arg0 = args[0].(state.Bullet)
+		}
+		var arg1 []string
+		if args[1] != nil {
+			arg1 = args[1].([]string)
 		}
 		run(
 			arg0,
+			arg1,
 		)
 	})
 	return _c
 }
 
-func (_c *MockGameStateRepository_SaveGameState_Call) Return() *MockGameStateRepository_SaveGameState_Call {
+func (_c *MockGameStateRepository_UpdateGameBullets_Call) Return() *MockGameStateRepository_UpdateGameBullets_Call {
 	_c.Call.Return()
 	return _c
 }
 
-func (_c *MockGameStateRepository_SaveGameState_Call) RunAndReturn(run func(gameState *state.GameState)) *MockGameStateRepository_SaveGameState_Call {
+func (_c *MockGameStateRepository_UpdateGameBullets_Call) RunAndReturn(run func(bullet state.Bullet, players []string)) *MockGameStateRepository_UpdateGameBullets_Call {
 	_c.Run(run)
 	return _c
 }
 
-// SendReceivedMessage provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) SendReceivedMessage(messageEncoded string) {
-	_mock.Called(messageEncoded)
+// UpdateGamePlayerState provides a mock function for the type MockGameStateRepository
+func (_mock *MockGameStateRepository) UpdateGamePlayerState(playerId string, position state.Position, players []string) {
+	_mock.Called(playerId, position, players)
 	return
 }
 
-// MockGameStateRepository_SendReceivedMessage_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SendReceivedMessage'
-type MockGameStateRepository_SendReceivedMessage_Call struct {
+// MockGameStateRepository_UpdateGamePlayerState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateGamePlayerState'
+type MockGameStateRepository_UpdateGamePlayerState_Call struct {
 	*mock.Call
 }
 
-// SendReceivedMessage is a helper method to define mock.On call
-//   - messageEncoded string
-func (_e *MockGameStateRepository_Expecter) SendReceivedMessage(messageEncoded interface{}) *MockGameStateRepository_SendReceivedMessage_Call {
-	return &MockGameStateRepository_SendReceivedMessage_Call{Call: _e.mock.On("SendReceivedMessage", messageEncoded)}
+// UpdateGamePlayerState is a helper method to define mock.On call
+//   - playerId string
+//   - position state.Position
+//   - players []string
+func (_e *MockGameStateRepository_Expecter) UpdateGamePlayerState(playerId interface{}, position interface{}, players interface{}) *MockGameStateRepository_UpdateGamePlayerState_Call {
+	return &MockGameStateRepository_UpdateGamePlayerState_Call{Call: _e.mock.On("UpdateGamePlayerState", playerId, position, players)}
 }
 
-func (_c *MockGameStateRepository_SendReceivedMessage_Call) Run(run func(messageEncoded string)) *MockGameStateRepository_SendReceivedMessage_Call {
+func (_c *MockGameStateRepository_UpdateGamePlayerState_Call) Run(run func(playerId string, position state.Position, players []string)) *MockGameStateRepository_UpdateGamePlayerState_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
 			arg0 = args[0].(string)
 		}
+		var arg1 state.Position
+		if args[1] != nil {
+			arg1 = args[1].(state.Position)
+		}
+		var arg2 []string
+		if args[2] != nil {
+			arg2 = args[2].([]string)
+		}
 		run(
 			arg0,
+			arg1,
+			arg2,
 		)
 	})
 	return _c
 }
 
-func (_c *MockGameStateRepository_SendReceivedMessage_Call) Return() *MockGameStateRepository_SendReceivedMessage_Call {
+func (_c *MockGameStateRepository_UpdateGamePlayerState_Call) Return() *MockGameStateRepository_UpdateGamePlayerState_Call {
 	_c.Call.Return()
 	return _c
 }
 
-func (_c *MockGameStateRepository_SendReceivedMessage_Call) RunAndReturn(run func(messageEncoded string)) *MockGameStateRepository_SendReceivedMessage_Call {
+func (_c *MockGameStateRepository_UpdateGamePlayerState_Call) RunAndReturn(run func(playerId string, position state.Position, players []string)) *MockGameStateRepository_UpdateGamePlayerState_Call {
 	_c.Run(run)
 	return _c
 }
 
-// SetLeaderElector provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) SetLeaderElector(elector LeaderElector) {
-	_mock.Called(elector)
-	return
+// NewMockInstanceRegistry creates a new instance of MockInstanceRegistry. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewMockInstanceRegistry(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *MockInstanceRegistry {
+	mock := &MockInstanceRegistry{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
+
+// MockInstanceRegistry is an autogenerated mock type for the InstanceRegistry type
+type MockInstanceRegistry struct {
+	mock.Mock
+}
+
+type MockInstanceRegistry_Expecter struct {
+	mock *mock.Mock
+}
+
+func (_m *MockInstanceRegistry) EXPECT() *MockInstanceRegistry_Expecter {
+	return &MockInstanceRegistry_Expecter{mock: &_m.Mock}
+}
+
+// Deregister provides a mock function for the type MockInstanceRegistry
+func (_mock *MockInstanceRegistry) Deregister(instanceId string) error {
+	ret := _mock.Called(instanceId)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Deregister")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(string) error); ok {
+		r0 = returnFunc(instanceId)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
 }
 
-// MockGameStateRepository_SetLeaderElector_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetLeaderElector'
-type MockGameStateRepository_SetLeaderElector_Call struct {
+// MockInstanceRegistry_Deregister_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Deregister'
+type MockInstanceRegistry_Deregister_Call struct {
 	*mock.Call
 }
 
-// SetLeaderElector is a helper method to define mock.On call
-//   - elector LeaderElector
-func (_e *MockGameStateRepository_Expecter) SetLeaderElector(elector interface{}) *MockGameStateRepository_SetLeaderElector_Call {
-	return &MockGameStateRepository_SetLeaderElector_Call{Call: _e.mock.On("SetLeaderElector", elector)}
+// Deregister is a helper method to define mock.On call
+//   - instanceId string
+func (_e *MockInstanceRegistry_Expecter) Deregister(instanceId interface{}) *MockInstanceRegistry_Deregister_Call {
+	return &MockInstanceRegistry_Deregister_Call{Call: _e.mock.On("Deregister", instanceId)}
 }
 
-func (_c *MockGameStateRepository_SetLeaderElector_Call) Run(run func(elector LeaderElector)) *MockGameStateRepository_SetLeaderElector_Call {
+func (_c *MockInstanceRegistry_Deregister_Call) Run(run func(instanceId string)) *MockInstanceRegistry_Deregister_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 LeaderElector
+		var arg0 string
 		if args[0] != nil {
-			arg0 = args[0].(LeaderElector)
+			arg0 = args[0].(string)
 		}
 		run(
 			arg0,
@@ -1669,93 +2977,104 @@ func (_c *MockGameStateRepository_SetLeaderElector_Call) Run(run func(elector Le
 	return _c
 }
 
-func (_c *MockGameStateRepository_SetLeaderElector_Call) Return() *MockGameStateRepository_SetLeaderElector_Call {
-	_c.Call.Return()
+func (_c *MockInstanceRegistry_Deregister_Call) Return(err error) *MockInstanceRegistry_Deregister_Call {
+	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockGameStateRepository_SetLeaderElector_Call) RunAndReturn(run func(elector LeaderElector)) *MockGameStateRepository_SetLeaderElector_Call {
-	_c.Run(run)
+func (_c *MockInstanceRegistry_Deregister_Call) RunAndReturn(run func(instanceId string) error) *MockInstanceRegistry_Deregister_Call {
+	_c.Call.Return(run)
 	return _c
 }
 
-// SubscribeMessages provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) SubscribeMessages() error {
+// ListInstances provides a mock function for the type MockInstanceRegistry
+func (_mock *MockInstanceRegistry) ListInstances() ([]InstanceInfo, error) {
 	ret := _mock.Called()
 
 	if len(ret) == 0 {
-		panic("no return value specified for SubscribeMessages")
+		panic("no return value specified for ListInstances")
 	}
 
-	var r0 error
-	if returnFunc, ok := ret.Get(0).(func() error); ok {
+	var r0 []InstanceInfo
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() ([]InstanceInfo, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() []InstanceInfo); ok {
 		r0 = returnFunc()
 	} else {
-		r0 = ret.Error(0)
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]InstanceInfo)
+		}
 	}
-	return r0
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// MockGameStateRepository_SubscribeMessages_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SubscribeMessages'
-type MockGameStateRepository_SubscribeMessages_Call struct {
+// MockInstanceRegistry_ListInstances_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ListInstances'
+type MockInstanceRegistry_ListInstances_Call struct {
 	*mock.Call
 }
 
-// SubscribeMessages is a helper method to define mock.On call
-func (_e *MockGameStateRepository_Expecter) SubscribeMessages() *MockGameStateRepository_SubscribeMessages_Call {
-	return &MockGameStateRepository_SubscribeMessages_Call{Call: _e.mock.On("SubscribeMessages")}
+// ListInstances is a helper method to define mock.On call
+func (_e *MockInstanceRegistry_Expecter) ListInstances() *MockInstanceRegistry_ListInstances_Call {
+	return &MockInstanceRegistry_ListInstances_Call{Call: _e.mock.On("ListInstances")}
 }
 
-func (_c *MockGameStateRepository_SubscribeMessages_Call) Run(run func()) *MockGameStateRepository_SubscribeMessages_Call {
+func (_c *MockInstanceRegistry_ListInstances_Call) Run(run func()) *MockInstanceRegistry_ListInstances_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		run()
 	})
 	return _c
 }
 
-func (_c *MockGameStateRepository_SubscribeMessages_Call) Return(err error) *MockGameStateRepository_SubscribeMessages_Call {
-	_c.Call.Return(err)
+func (_c *MockInstanceRegistry_ListInstances_Call) Return(instanceInfos []InstanceInfo, err error) *MockInstanceRegistry_ListInstances_Call {
+	_c.Call.Return(instanceInfos, err)
 	return _c
 }
 
-func (_c *MockGameStateRepository_SubscribeMessages_Call) RunAndReturn(run func() error) *MockGameStateRepository_SubscribeMessages_Call {
+func (_c *MockInstanceRegistry_ListInstances_Call) RunAndReturn(run func() ([]InstanceInfo, error)) *MockInstanceRegistry_ListInstances_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// TryToBecomeLeader provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) TryToBecomeLeader(roomID string) bool {
-	ret := _mock.Called(roomID)
+// Register provides a mock function for the type MockInstanceRegistry
+func (_mock *MockInstanceRegistry) Register(info InstanceInfo) error {
+	ret := _mock.Called(info)
 
 	if len(ret) == 0 {
-		panic("no return value specified for TryToBecomeLeader")
+		panic("no return value specified for Register")
 	}
 
-	var r0 bool
-	if returnFunc, ok := ret.Get(0).(func(string) bool); ok {
-		r0 = returnFunc(roomID)
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(InstanceInfo) error); ok {
+		r0 = returnFunc(info)
 	} else {
-		r0 = ret.Get(0).(bool)
+		r0 = ret.Error(0)
 	}
 	return r0
 }
 
-// MockGameStateRepository_TryToBecomeLeader_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TryToBecomeLeader'
-type MockGameStateRepository_TryToBecomeLeader_Call struct {
+// MockInstanceRegistry_Register_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Register'
+type MockInstanceRegistry_Register_Call struct {
 	*mock.Call
 }
 
-// TryToBecomeLeader is a helper method to define mock.On call
-//   - roomID string
-func (_e *MockGameStateRepository_Expecter) TryToBecomeLeader(roomID interface{}) *MockGameStateRepository_TryToBecomeLeader_Call {
-	return &MockGameStateRepository_TryToBecomeLeader_Call{Call: _e.mock.On("TryToBecomeLeader", roomID)}
+// Register is a helper method to define mock.On call
+//   - info InstanceInfo
+func (_e *MockInstanceRegistry_Expecter) Register(info interface{}) *MockInstanceRegistry_Register_Call {
+	return &MockInstanceRegistry_Register_Call{Call: _e.mock.On("Register", info)}
 }
 
-func (_c *MockGameStateRepository_TryToBecomeLeader_Call) Run(run func(roomID string)) *MockGameStateRepository_TryToBecomeLeader_Call {
+func (_c *MockInstanceRegistry_Register_Call) Run(run func(info InstanceInfo)) *MockInstanceRegistry_Register_Call {
 	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 string
+		var arg0 InstanceInfo
 		if args[0] != nil {
-			arg0 = args[0].(string)
+			arg0 = args[0].(InstanceInfo)
 		}
 		run(
 			arg0,
@@ -1764,111 +3083,79 @@ func (_c *MockGameStateRepository_TryToBecomeLeader_Call) Run(run func(roomID st
 	return _c
 }
 
-func (_c *MockGameStateRepository_TryToBecomeLeader_Call) Return(b bool) *MockGameStateRepository_TryToBecomeLeader_Call {
-	_c.Call.Return(b)
+func (_c *MockInstanceRegistry_Register_Call) Return(err error) *MockInstanceRegistry_Register_Call {
+	_c.Call.Return(err)
 	return _c
 }
 
-func (_c *MockGameStateRepository_TryToBecomeLeader_Call) RunAndReturn(run func(roomID string) bool) *MockGameStateRepository_TryToBecomeLeader_Call {
+func (_c *MockInstanceRegistry_Register_Call) RunAndReturn(run func(info InstanceInfo) error) *MockInstanceRegistry_Register_Call {
 	_c.Call.Return(run)
 	return _c
 }
 
-// UpdateGameBullets provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) UpdateGameBullets(bullet state.Bullet, players []string) {
-	_mock.Called(bullet, players)
-	return
-}
-
-// MockGameStateRepository_UpdateGameBullets_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateGameBullets'
-type MockGameStateRepository_UpdateGameBullets_Call struct {
-	*mock.Call
-}
-
-// UpdateGameBullets is a helper method to define mock.On call
-//   - bullet state.Bullet
-//   - players []string
-func (_e *MockGameStateRepository_Expecter) UpdateGameBullets(bullet interface{}, players interface{}) *MockGameStateRepository_UpdateGameBullets_Call {
-	return &MockGameStateRepository_UpdateGameBullets_Call{Call: _e.mock.On("UpdateGameBullets", bullet, players)}
-}
-
-func (_c *MockGameStateRepository_UpdateGameBullets_Call) Run(run func(bullet state.Bullet, players []string)) *MockGameStateRepository_UpdateGameBullets_Call {
-	_c.Call.Run(func(args mock.Arguments) {
-		var arg0 state.Bullet
-		if args[0] != nil {
-			arg0 = args[0].(state.Bullet)
-		}
-		var arg1 []string
-		if args[1] != nil {
-			arg1 = args[1].([]string)
-		}
-		run(
-			arg0,
-			arg1,
-		)
-	})
-	return _c
-}
-
-func (_c *MockGameStateRepository_UpdateGameBullets_Call) Return() *MockGameStateRepository_UpdateGameBullets_Call {
-	_c.Call.Return()
-	return _c
-}
+// TryLock provides a mock function for the type MockInstanceRegistry
+func (_mock *MockInstanceRegistry) TryLock(name string, ttl time.Duration) (bool, error) {
+	ret := _mock.Called(name, ttl)
 
-func (_c *MockGameStateRepository_UpdateGameBullets_Call) RunAndReturn(run func(bullet state.Bullet, players []string)) *MockGameStateRepository_UpdateGameBullets_Call {
-	_c.Run(run)
-	return _c
-}
+	if len(ret) == 0 {
+		panic("no return value specified for TryLock")
+	}
 
-// UpdateGamePlayerState provides a mock function for the type MockGameStateRepository
-func (_mock *MockGameStateRepository) UpdateGamePlayerState(playerId string, position state.Position, players []string) {
-	_mock.Called(playerId, position, players)
-	return
+	var r0 bool
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string, time.Duration) (bool, error)); ok {
+		return returnFunc(name, ttl)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string, time.Duration) bool); ok {
+		r0 = returnFunc(name, ttl)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string, time.Duration) error); ok {
+		r1 = returnFunc(name, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
 }
 
-// MockGameStateRepository_UpdateGamePlayerState_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'UpdateGamePlayerState'
-type MockGameStateRepository_UpdateGamePlayerState_Call struct {
+// MockInstanceRegistry_TryLock_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'TryLock'
+type MockInstanceRegistry_TryLock_Call struct {
 	*mock.Call
 }
 
-// UpdateGamePlayerState is a helper method to define mock.On call
-//   - playerId string
-//   - position state.Position
-//   - players []string
-func (_e *MockGameStateRepository_Expecter) UpdateGamePlayerState(playerId interface{}, position interface{}, players interface{}) *MockGameStateRepository_UpdateGamePlayerState_Call {
-	return &MockGameStateRepository_UpdateGamePlayerState_Call{Call: _e.mock.On("UpdateGamePlayerState", playerId, position, players)}
+// TryLock is a helper method to define mock.On call
+//   - name string
+//   - ttl time.Duration
+func (_e *MockInstanceRegistry_Expecter) TryLock(name interface{}, ttl interface{}) *MockInstanceRegistry_TryLock_Call {
+	return &MockInstanceRegistry_TryLock_Call{Call: _e.mock.On("TryLock", name, ttl)}
 }
 
-func (_c *MockGameStateRepository_UpdateGamePlayerState_Call) Run(run func(playerId string, position state.Position, players []string)) *MockGameStateRepository_UpdateGamePlayerState_Call {
+func (_c *MockInstanceRegistry_TryLock_Call) Run(run func(name string, ttl time.Duration)) *MockInstanceRegistry_TryLock_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
 			arg0 = args[0].(string)
 		}
-		var arg1 state.Position
+		var arg1 time.Duration
 		if args[1] != nil {
-			arg1 = args[1].(state.Position)
-		}
-		var arg2 []string
-		if args[2] != nil {
-			arg2 = args[2].([]string)
+			arg1 = args[1].(time.Duration)
 		}
 		run(
 			arg0,
 			arg1,
-			arg2,
 		)
 	})
 	return _c
 }
 
-func (_c *MockGameStateRepository_UpdateGamePlayerState_Call) Return() *MockGameStateRepository_UpdateGamePlayerState_Call {
-	_c.Call.Return()
+func (_c *MockInstanceRegistry_TryLock_Call) Return(b bool, err error) *MockInstanceRegistry_TryLock_Call {
+	_c.Call.Return(b, err)
 	return _c
 }
 
-func (_c *MockGameStateRepository_UpdateGamePlayerState_Call) RunAndReturn(run func(playerId string, position state.Position, players []string)) *MockGameStateRepository_UpdateGamePlayerState_Call {
-	_c.Run(run)
+func (_c *MockInstanceRegistry_TryLock_Call) RunAndReturn(run func(name string, ttl time.Duration) (bool, error)) *MockInstanceRegistry_TryLock_Call {
+	_c.Call.Return(run)
 	return _c
 }
 
@@ -2091,6 +3378,57 @@ func (_c *MockRoomRepository_CreatePlayer_Call) RunAndReturn(run func(id int) (*
 	return _c
 }
 
+// DeleteLobbyRoom provides a mock function for the type MockRoomRepository
+func (_mock *MockRoomRepository) DeleteLobbyRoom(room *Room) error {
+	ret := _mock.Called(room)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteLobbyRoom")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(*Room) error); ok {
+		r0 = returnFunc(room)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockRoomRepository_DeleteLobbyRoom_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'DeleteLobbyRoom'
+type MockRoomRepository_DeleteLobbyRoom_Call struct {
+	*mock.Call
+}
+
+// DeleteLobbyRoom is a helper method to define mock.On call
+//   - room *Room
+func (_e *MockRoomRepository_Expecter) DeleteLobbyRoom(room interface{}) *MockRoomRepository_DeleteLobbyRoom_Call {
+	return &MockRoomRepository_DeleteLobbyRoom_Call{Call: _e.mock.On("DeleteLobbyRoom", room)}
+}
+
+func (_c *MockRoomRepository_DeleteLobbyRoom_Call) Run(run func(room *Room)) *MockRoomRepository_DeleteLobbyRoom_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 *Room
+		if args[0] != nil {
+			arg0 = args[0].(*Room)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockRoomRepository_DeleteLobbyRoom_Call) Return(err error) *MockRoomRepository_DeleteLobbyRoom_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockRoomRepository_DeleteLobbyRoom_Call) RunAndReturn(run func(room *Room) error) *MockRoomRepository_DeleteLobbyRoom_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // DeletePlayerRoom provides a mock function for the type MockRoomRepository
 func (_mock *MockRoomRepository) DeletePlayerRoom(playerId string) error {
 	ret := _mock.Called(playerId)
@@ -2255,6 +3593,61 @@ func (_c *MockRoomRepository_GetPlayerRoom_Call) RunAndReturn(run func(playerId
 	return _c
 }
 
+// GetPlayerRooms provides a mock function for the type MockRoomRepository
+func (_mock *MockRoomRepository) GetPlayerRooms() (map[string]string, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetPlayerRooms")
+	}
+
+	var r0 map[string]string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() (map[string]string, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() map[string]string); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]string)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRoomRepository_GetPlayerRooms_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetPlayerRooms'
+type MockRoomRepository_GetPlayerRooms_Call struct {
+	*mock.Call
+}
+
+// GetPlayerRooms is a helper method to define mock.On call
+func (_e *MockRoomRepository_Expecter) GetPlayerRooms() *MockRoomRepository_GetPlayerRooms_Call {
+	return &MockRoomRepository_GetPlayerRooms_Call{Call: _e.mock.On("GetPlayerRooms")}
+}
+
+func (_c *MockRoomRepository_GetPlayerRooms_Call) Run(run func()) *MockRoomRepository_GetPlayerRooms_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockRoomRepository_GetPlayerRooms_Call) Return(sToV map[string]string, err error) *MockRoomRepository_GetPlayerRooms_Call {
+	_c.Call.Return(sToV, err)
+	return _c
+}
+
+func (_c *MockRoomRepository_GetPlayerRooms_Call) RunAndReturn(run func() (map[string]string, error)) *MockRoomRepository_GetPlayerRooms_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetRoom provides a mock function for the type MockRoomRepository
 func (_mock *MockRoomRepository) GetRoom(key string) (*Room, error) {
 	ret := _mock.Called(key)
@@ -2317,6 +3710,61 @@ func (_c *MockRoomRepository_GetRoom_Call) RunAndReturn(run func(key string) (*R
 	return _c
 }
 
+// GetRoomIDs provides a mock function for the type MockRoomRepository
+func (_mock *MockRoomRepository) GetRoomIDs() ([]string, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetRoomIDs")
+	}
+
+	var r0 []string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() ([]string, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() []string); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockRoomRepository_GetRoomIDs_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetRoomIDs'
+type MockRoomRepository_GetRoomIDs_Call struct {
+	*mock.Call
+}
+
+// GetRoomIDs is a helper method to define mock.On call
+func (_e *MockRoomRepository_Expecter) GetRoomIDs() *MockRoomRepository_GetRoomIDs_Call {
+	return &MockRoomRepository_GetRoomIDs_Call{Call: _e.mock.On("GetRoomIDs")}
+}
+
+func (_c *MockRoomRepository_GetRoomIDs_Call) Run(run func()) *MockRoomRepository_GetRoomIDs_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockRoomRepository_GetRoomIDs_Call) Return(strings []string, err error) *MockRoomRepository_GetRoomIDs_Call {
+	_c.Call.Return(strings, err)
+	return _c
+}
+
+func (_c *MockRoomRepository_GetRoomIDs_Call) RunAndReturn(run func() ([]string, error)) *MockRoomRepository_GetRoomIDs_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetRooms provides a mock function for the type MockRoomRepository
 func (_mock *MockRoomRepository) GetRooms(page int, pageSize int) (*[]Room, error) {
 	ret := _mock.Called(page, pageSize)
@@ -2386,8 +3834,8 @@ func (_c *MockRoomRepository_GetRooms_Call) RunAndReturn(run func(page int, page
 }
 
 // PublishToRoom provides a mock function for the type MockRoomRepository
-func (_mock *MockRoomRepository) PublishToRoom(payload string) {
-	_mock.Called(payload)
+func (_mock *MockRoomRepository) PublishToRoom(roomId string, payload string) {
+	_mock.Called(roomId, payload)
 	return
 }
 
@@ -2397,19 +3845,25 @@ type MockRoomRepository_PublishToRoom_Call struct {
 }
 
 // PublishToRoom is a helper method to define mock.On call
+//   - roomId string
 //   - payload string
-func (_e *MockRoomRepository_Expecter) PublishToRoom(payload interface{}) *MockRoomRepository_PublishToRoom_Call {
-	return &MockRoomRepository_PublishToRoom_Call{Call: _e.mock.On("PublishToRoom", payload)}
+func (_e *MockRoomRepository_Expecter) PublishToRoom(roomId interface{}, payload interface{}) *MockRoomRepository_PublishToRoom_Call {
+	return &MockRoomRepository_PublishToRoom_Call{Call: _e.mock.On("PublishToRoom", roomId, payload)}
 }
 
-func (_c *MockRoomRepository_PublishToRoom_Call) Run(run func(payload string)) *MockRoomRepository_PublishToRoom_Call {
+func (_c *MockRoomRepository_PublishToRoom_Call) Run(run func(roomId string, payload string)) *MockRoomRepository_PublishToRoom_Call {
 	_c.Call.Run(func(args mock.Arguments) {
 		var arg0 string
 		if args[0] != nil {
 			arg0 = args[0].(string)
 		}
+		var arg1 string
+		if args[1] != nil {
+			arg1 = args[1].(string)
+		}
 		run(
 			arg0,
+			arg1,
 		)
 	})
 	return _c
@@ -2420,7 +3874,7 @@ func (_c *MockRoomRepository_PublishToRoom_Call) Return() *MockRoomRepository_Pu
 	return _c
 }
 
-func (_c *MockRoomRepository_PublishToRoom_Call) RunAndReturn(run func(payload string)) *MockRoomRepository_PublishToRoom_Call {
+func (_c *MockRoomRepository_PublishToRoom_Call) RunAndReturn(run func(roomId string, payload string)) *MockRoomRepository_PublishToRoom_Call {
 	_c.Run(run)
 	return _c
 }
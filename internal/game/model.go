@@ -25,6 +25,32 @@ type Room struct {
 	Team2    []Player `json:"team2"`
 	Host     Player   `json:"host"`
 	Status   string   `json:"status"`
+	Ready    []string `json:"ready"`
+	Version  int      `json:"version"`
+}
+
+// SetReady marks a player as ready or not ready to start the game
+func (r *Room) SetReady(playerId string, ready bool) {
+	ids := make([]string, 0, len(r.Ready)+1)
+	for _, id := range r.Ready {
+		if id != playerId {
+			ids = append(ids, id)
+		}
+	}
+	if ready {
+		ids = append(ids, playerId)
+	}
+	r.Ready = ids
+}
+
+// IsReady reports if a player marked itself as ready
+func (r *Room) IsReady(playerId string) bool {
+	for _, id := range r.Ready {
+		if id == playerId {
+			return true
+		}
+	}
+	return false
 }
 
 type RoomPageRequest struct {
@@ -42,6 +68,20 @@ type LeaveRoomMessage struct {
 	Host   Player `json:"host"`
 }
 
+type TeamSwitchMessage struct {
+	Player Player `json:"player"`
+	Team   int    `json:"team"`
+}
+
+type ReadyMessage struct {
+	Player string `json:"player"`
+	Ready  bool   `json:"ready"`
+}
+
+type RoomDeletedMessage struct {
+	RoomId string `json:"roomId"`
+}
+
 type KickPlayerMessage struct {
 	Room   string `json:"roomId"`
 	Kicked string `json:"kicked"`
@@ -52,6 +92,15 @@ type MoveMessage struct {
 	Position interface{} `json:"position"`
 }
 
+type PlayerConnectionMessage struct {
+	PlayerId string `json:"playerId"`
+}
+
+type LatencyMessage struct {
+	PlayerId string `json:"playerId"`
+	Latency  int64  `json:"latency"`
+}
+
 type ShootMessage struct {
 	ID       string      `json:"id"`
 	Position interface{} `json:"position"`
@@ -63,6 +112,8 @@ type GameMessage struct {
 	Type    string      `json:"type"`
 	Payload interface{} `json:"payload"`
 	Users   []string    `json:"users"`
+	Room    string      `json:"room,omitempty"`
+	Fence   int64       `json:"fence,omitempty"`
 }
 
 type GameStateMessage struct {
@@ -1,23 +1,34 @@
 package game
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
-
-	"github.com/thesrcielos/TopTankBattle/websocket/transport"
+	"github.com/thesrcielos/TopTankBattle/pkg/db"
 )
 
 var instanceID = getEnv("INSTANCE_ID", uuid.New().String())
 
+// checkpointExpiration keeps the checkpoint of an abandoned game from living forever
+const checkpointExpiration = 1 * time.Minute
+
+// fenceExpiration keeps the fencing counter of a finished game long enough to outlive any stale leader
+const fenceExpiration = 1 * time.Hour
+
+// leaderExpiration is the lease of a room leadership, the leader renews it every tick
+const leaderExpiration = 5000 * time.Millisecond
+
+// ErrStaleLeader is returned when a write carries a fencing token older than the current leader's
+var ErrStaleLeader = apperrors.NewAppError(409, "Instance is no longer the room leader", errors.New("stale fencing token"))
+
 func getEnv(key, fallback string) string {
 	if val := os.Getenv(key); val != "" {
 		return val
@@ -25,275 +36,206 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
-func NewGameStateRepository(leaderElector LeaderElector, db *redis.Client) *RedisGameStateRepository {
+func NewGameStateRepository(leaderElector LeaderElector, db *redis.Client, bus MessageBus) *RedisGameStateRepository {
 	return &RedisGameStateRepository{
-		LeaderElector: leaderElector,
-		db:            db,
+		roomEvents: newRoomEvents(leaderElector, bus),
+		db:         db,
 	}
 }
 
 type GameStateRepository interface {
-	PublishToRoom(payload string)
-	SubscribeMessages() error
+	PublishToRoom(roomID string, payload string)
+	PublishControl(payload string)
+	SubscribeRoom(roomID string) error
+	UnsubscribeRoom(roomID string) error
 	SendReceivedMessage(messageEncoded string)
-	TryToBecomeLeader(roomID string) bool
-	SaveGameState(gameState *state.GameState)
-	RestoreGameState(roomID string) *state.GameState
-	RenewLeadership(roomID string, expiration time.Duration) (bool, error)
+	TryToBecomeLeader(roomID string) (int64, bool)
+	SaveGameState(gameState *state.GameState) error
+	RestoreGameState(roomID string) (*state.GameState, error)
+	RenewLeadership(roomID string, token int64, expiration time.Duration) (bool, error)
+	ReleaseLeadership(roomID string, token int64) error
+	DeleteGameState(roomID string, token int64) error
+	GetLeader(roomID string) (string, error)
 	UpdateGamePlayerState(playerId string, position state.Position, players []string)
 	UpdateGameBullets(bullet state.Bullet, players []string)
 	SetLeaderElector(elector LeaderElector)
+	SetLeaderLocator(locator *LeaderLocator)
 }
 
+// RedisGameStateRepository keeps leaderships and checkpoints in Redis
 type RedisGameStateRepository struct {
-	LeaderElector LeaderElector
-	db            *redis.Client
+	*roomEvents
+	db *redis.Client
 }
 
-func (r *RedisGameStateRepository) SetLeaderElector(elector LeaderElector) {
-	r.LeaderElector = elector
+type MovePlayerMessage struct {
+	PlayerId string         `json:"playerId"`
+	Position state.Position `json:"position"`
 }
-func (r *RedisGameStateRepository) PublishToRoom(payload string) {
-	err := r.db.Publish(ctx, "messages", payload).Err()
-	if err != nil {
-		log.Println("Error publishing to room:", err)
-	}
+
+type GameInfo struct {
+	Instance string `json:"instance"`
+	RoomId   string `json:"roomId"`
 }
 
-func (r *RedisGameStateRepository) SubscribeMessages() error {
-	sub := r.db.Subscribe(ctx, "messages")
-	_, err := sub.Receive(ctx)
+// acquireLeaderScript takes or keeps the leadership of a room. A new leader gets the next
+// value of the room fencing counter, the current leader keeps its token and extends the lease.
+// It returns 0 when another instance holds the leadership.
+var acquireLeaderScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local owner, token = string.match(current, '^(.*):(%d+)$')
+	if owner == ARGV[1] then
+		redis.call('PEXPIRE', KEYS[1], ARGV[2])
+		return tonumber(token)
+	end
+	return 0
+end
+local token = redis.call('INCR', KEYS[2])
+redis.call('PERSIST', KEYS[2])
+redis.call('SET', KEYS[1], ARGV[1] .. ':' .. token, 'PX', ARGV[2])
+redis.call('SADD', KEYS[3], KEYS[1])
+return token
+`)
+
+// renewLeaderScript extends the lease only if the caller still owns it with the same token
+var renewLeaderScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// releaseLeaderScript deletes the leader key only if the caller still owns it with the same token
+var releaseLeaderScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// saveCheckpointScript rejects the write when a newer leader has already been elected
+var saveCheckpointScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[2]) or '0')
+if tonumber(ARGV[1]) < current then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+redis.call('SADD', KEYS[3], KEYS[1])
+return 1
+`)
+
+// deleteGameStateScript removes every key in the index of a room unless a newer leader was elected.
+// The fencing counter is not indexed, it only expires so the tokens of the room keep growing.
+var deleteGameStateScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[2]) or '0')
+if tonumber(ARGV[1]) < current then
+	return 0
+end
+local keys = redis.call('SMEMBERS', KEYS[1])
+if #keys > 0 then
+	redis.call('DEL', unpack(keys))
+end
+redis.call('DEL', KEYS[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+return 1
+`)
+
+// leaderKeys are the leader key, the fencing counter and the key index of a room
+func leaderKeys(roomID string) []string {
+	return []string{db.LeaderKey(roomID), db.FenceKey(roomID), db.RoomKeysKey(roomID)}
+}
+
+func leaderValue(token int64) string {
+	return fmt.Sprintf("%s:%d", instanceID, token)
+}
+
+func (r *RedisGameStateRepository) TryToBecomeLeader(roomID string) (int64, bool) {
+	token, err := acquireLeaderScript.Run(ctx, r.db, leaderKeys(roomID), instanceID, leaderExpiration.Milliseconds()).Int64()
 	if err != nil {
-		log.Println("error subscribing", err)
-		return fmt.Errorf("error subscribing %w", err)
+		log.Println("Error acquiring leadership:", err)
+		return 0, false
 	}
-
-	ch := sub.Channel()
-
-	log.Printf("Subscribed to messages channel")
-	go func() {
-		for msg := range ch {
-			r.SendReceivedMessage(msg.Payload)
-		}
-	}()
-
-	return nil
+	return token, token > 0
 }
 
-func (r *RedisGameStateRepository) SendReceivedMessage(messageEncoded string) {
-	var message GameMessage
-	if err := json.Unmarshal([]byte(messageEncoded), &message); err != nil {
-		log.Println("Error decoding message:", err)
-		return
-	}
-	fmt.Println("Received message:", message.Type, "for players:", message.Users)
-	if message.Type == "GAME_MOVE" {
-		payloadBytes, _ := json.Marshal(message.Payload)
-		var move MovePlayerMessage
-		json.Unmarshal(payloadBytes, &move)
-		r.UpdateGamePlayerState(move.PlayerId, move.Position, message.Users)
-		return
-	}
-	if message.Type == "GAME_SHOOT" {
-		payloadBytes, _ := json.Marshal(message.Payload)
-		var bullet state.Bullet
-		json.Unmarshal(payloadBytes, &bullet)
-		r.UpdateGameBullets(bullet, message.Users)
-		return
+func (r *RedisGameStateRepository) SaveGameState(gameState *state.GameState) error {
+	data, err := state.NewCheckpoint(gameState).Encode()
+	if err != nil {
+		return apperrors.NewAppError(500, "Error encoding game checkpoint", err)
 	}
-	if message.Type == "GAME_START_INFO" {
-		payloadBytes, _ := json.Marshal(message.Payload)
-		var info GameInfo
-		err := json.Unmarshal(payloadBytes, &info)
-		if err != nil {
-			log.Println("Error decoding game start info:", err)
-			return
-		}
-		if info.Instance != instanceID {
-			go r.LeaderElector.AttemptLeadership(info.RoomId)
-		}
-		return
+
+	keys := []string{db.CheckpointKey(gameState.RoomId), db.FenceKey(gameState.RoomId), db.RoomKeysKey(gameState.RoomId)}
+	saved, err := saveCheckpointScript.Run(ctx, r.db, keys, gameState.Fence, data, checkpointExpiration.Milliseconds()).Int()
+	if err != nil {
+		return apperrors.NewAppError(500, "Error saving game checkpoint", err)
 	}
-	msg := transport.OutgoingMessage{
-		Type:    message.Type,
-		Payload: message.Payload,
+	if saved == 0 {
+		return ErrStaleLeader
 	}
 
-	for _, playerId := range message.Users {
-		transport.SendToPlayer(playerId, msg)
-	}
+	return nil
 }
 
-func (r *RedisGameStateRepository) UpdateGamePlayerState(playerId string, position state.Position, players []string) {
-	players = append(players, playerId)
-	for _, id := range players {
-		player := state.GetPlayer(id)
-		if player == nil || player.GameState == nil {
-			return
-		}
-		game := player.GameState
-		game.GameMu.Lock()
-		game.Players[playerId].Position = position
-		game.GameMu.Unlock()
-		break
+func (r *RedisGameStateRepository) RestoreGameState(roomID string) (*state.GameState, error) {
+	data, err := r.db.Get(ctx, db.CheckpointKey(roomID)).Bytes()
+	if err == redis.Nil {
+		return nil, apperrors.NewAppError(404, "Game checkpoint not found", errors.New("checkpoint not found"))
+	} else if err != nil {
+		return nil, apperrors.NewAppError(500, "Error getting game checkpoint", err)
 	}
-}
 
-func (r *RedisGameStateRepository) UpdateGameBullets(bullet state.Bullet, players []string) {
-	players = append(players, bullet.OwnerId)
-	for _, playerId := range players {
-		player := state.GetPlayer(playerId)
-		if player == nil || player.GameState == nil {
-			continue
-		}
-		game := player.GameState
-		game.GameMu.Lock()
-		game.Bullets[bullet.ID] = &bullet
-		game.GameMu.Unlock()
-		return
+	checkpoint, err := state.DecodeCheckpoint(data)
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error restoring game checkpoint", err)
 	}
 
+	return checkpoint.GameState(), nil
 }
 
-type MovePlayerMessage struct {
-	PlayerId string         `json:"playerId"`
-	Position state.Position `json:"position"`
-}
-
-type GameInfo struct {
-	Instance string `json:"instance"`
-	RoomId   string `json:"roomId"`
-}
-
-func (r *RedisGameStateRepository) TryToBecomeLeader(roomID string) bool {
-	key := fmt.Sprintf("leader:%s", roomID)
-	ok, err := r.db.SetNX(ctx, key, instanceID, 5000*time.Millisecond).Result()
+func (r *RedisGameStateRepository) RenewLeadership(roomID string, token int64, expiration time.Duration) (bool, error) {
+	renewed, err := renewLeaderScript.Run(ctx, r.db, leaderKeys(roomID)[:1], leaderValue(token), expiration.Milliseconds()).Int()
 	if err != nil {
-		return false
-	}
-	return ok
-}
-
-func (r *RedisGameStateRepository) SaveGameState(gameState *state.GameState) {
-	roomID := gameState.RoomId
-	for _, b := range gameState.Bullets {
-		key := fmt.Sprintf("room:%s:bullet:%s", roomID, b.ID)
-		r.db.HSet(ctx, key, map[string]interface{}{
-			"x":       b.Position.X,
-			"y":       b.Position.Y,
-			"angle":   b.Position.Angle,
-			"speed":   b.Speed,
-			"ownerId": b.OwnerId,
-		})
-		r.db.Expire(ctx, key, 10*time.Second)
-	}
-
-	for _, p := range gameState.Players {
-		key := fmt.Sprintf("room:%s:player:%s", roomID, p.ID)
-		r.db.HSet(ctx, key, map[string]interface{}{
-			"x":     p.Position.X,
-			"y":     p.Position.Y,
-			"angle": p.Position.Angle,
-		})
+		return false, err
 	}
 
-	for _, f := range gameState.Fortresses {
-		key := fmt.Sprintf("room:%s:fortress:%s", roomID, f.ID)
-		r.db.HSet(ctx, key, map[string]interface{}{
-			"x":      f.Position.X,
-			"y":      f.Position.Y,
-			"health": f.Health,
-			"team1":  f.Team1,
-		})
-	}
+	return renewed == 1, nil
 }
 
-func (r *RedisGameStateRepository) RestoreGameState(roomID string) *state.GameState {
-	gameState := state.GameState{
-		RoomId:     roomID,
-		Bullets:    make(map[string]*state.Bullet),
-		Players:    make(map[string]*state.PlayerState),
-		Fortresses: make([]*state.Fortress, 0),
+func (r *RedisGameStateRepository) ReleaseLeadership(roomID string, token int64) error {
+	if err := releaseLeaderScript.Run(ctx, r.db, leaderKeys(roomID)[:1], leaderValue(token)).Err(); err != nil {
+		return apperrors.NewAppError(500, "Error releasing leadership", err)
 	}
 
-	keys, _ := r.db.Keys(ctx, fmt.Sprintf("room:%s:bullet:*", roomID)).Result()
-	for _, key := range keys {
-		vals, _ := r.db.HGetAll(ctx, key).Result()
-		b := state.Bullet{
-			ID: key[len(fmt.Sprintf("room:%s:bullet:", roomID)):],
-			Position: state.Position{
-				X:     parseFloat(vals["x"]),
-				Y:     parseFloat(vals["y"]),
-				Angle: parseFloat(vals["angle"]),
-			},
-			Speed:   parseFloat(vals["speed"]),
-			OwnerId: vals["ownerId"],
-		}
-		gameState.Bullets[b.ID] = &b
-	}
+	return nil
+}
 
-	keys, _ = r.db.Keys(ctx, fmt.Sprintf("room:%s:player:*", roomID)).Result()
-	for _, key := range keys {
-		vals, _ := r.db.HGetAll(ctx, key).Result()
-		p := state.PlayerState{
-			ID: key[len(fmt.Sprintf("room:%s:player:", roomID)):],
-			Position: state.Position{
-				X:     parseFloat(vals["x"]),
-				Y:     parseFloat(vals["y"]),
-				Angle: parseFloat(vals["angle"]),
-			},
-		}
-		gameState.Players[p.ID] = &p
+// DeleteGameState removes the checkpoint and the leader key of a finished game
+func (r *RedisGameStateRepository) DeleteGameState(roomID string, token int64) error {
+	keys := []string{db.RoomKeysKey(roomID), db.FenceKey(roomID)}
+	deleted, err := deleteGameStateScript.Run(ctx, r.db, keys, token, fenceExpiration.Milliseconds()).Int()
+	if err != nil {
+		return apperrors.NewAppError(500, "Error deleting game state", err)
 	}
-
-	keys, _ = r.db.Keys(ctx, fmt.Sprintf("room:%s:fortress:*", roomID)).Result()
-	for _, key := range keys {
-		vals, _ := r.db.HGetAll(ctx, key).Result()
-		f := state.Fortress{
-			ID: key[len(fmt.Sprintf("room:%s:fortress:", roomID)):],
-			Position: state.Position{
-				X:     parseFloat(vals["x"]),
-				Y:     parseFloat(vals["y"]),
-				Angle: 0, // Fortress does not have an angle
-			},
-			Health: parseInt(vals["health"]),
-			Team1:  vals["team1"] == "true",
-		}
-		gameState.Fortresses = append(gameState.Fortresses, &f)
+	if deleted == 0 {
+		return ErrStaleLeader
 	}
-	return &gameState
-}
 
-func parseInt(s string) int {
-	v, _ := strconv.Atoi(s)
-	return v
-}
-func parseFloat(s string) float64 {
-	v, _ := strconv.ParseFloat(s, 64)
-	return v
+	return nil
 }
 
-func (r *RedisGameStateRepository) RenewLeadership(roomID string, expiration time.Duration) (bool, error) {
-	key := fmt.Sprintf("leader:%s", roomID)
-
-	currentLeader, err := r.db.Get(ctx, key).Result()
+// GetLeader returns the instance leading a room, empty when nobody leads it
+func (r *RedisGameStateRepository) GetLeader(roomID string) (string, error) {
+	value, err := r.db.Get(ctx, leaderKeys(roomID)[0]).Result()
 	if err == redis.Nil {
-		fmt.Println("No current leader found")
-		if r.TryToBecomeLeader(roomID) {
-			return true, nil
-		}
-		return false, nil
+		return "", nil
 	} else if err != nil {
-		return false, err
+		return "", apperrors.NewAppError(500, "Error getting room leader", err)
 	}
 
-	if currentLeader == instanceID {
-		ok, err := r.db.Expire(ctx, key, expiration).Result()
-		if !ok || err != nil {
-			return false, errors.New("failed to renew leadership")
-		}
-		return true, nil
+	owner := value
+	if i := strings.LastIndex(value, ":"); i >= 0 {
+		owner = value[:i]
 	}
-
-	return false, nil
+	return owner, nil
 }
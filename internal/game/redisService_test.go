@@ -0,0 +1,100 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+)
+
+func TestAcceptFenceRejectsStaleLeader(t *testing.T) {
+	repo := NewGameStateRepository(nil, nil, NewMemoryMessageBus())
+
+	assert.True(t, repo.acceptFence("room1", 2))
+	assert.True(t, repo.acceptFence("room1", 2))
+	assert.False(t, repo.acceptFence("room1", 1))
+	assert.True(t, repo.acceptFence("room1", 3))
+	assert.True(t, repo.acceptFence("room2", 1))
+}
+
+func TestAcceptFenceIgnoresUnfencedMessages(t *testing.T) {
+	repo := NewGameStateRepository(nil, nil, NewMemoryMessageBus())
+
+	assert.True(t, repo.acceptFence("room1", 5))
+	assert.True(t, repo.acceptFence("room1", 0))
+	assert.True(t, repo.acceptFence("", 1))
+}
+
+func TestLatencyMessageUpdatesLedGame(t *testing.T) {
+	repo := NewGameStateRepository(nil, nil, NewMemoryMessageBus())
+	game := &state.GameState{
+		RoomId:  "latencyRoom",
+		Players: map[string]*state.PlayerState{"lat1": {ID: "lat1"}},
+		Bullets: map[string]*state.Bullet{},
+	}
+	state.RegisterPlayer("lat1", "latencyRoom", nil, "")
+	defer state.UnregisterPlayer("lat1")
+	state.GetPlayer("lat1").GameState = game
+
+	repo.SendReceivedMessage(`{"type":"LATENCY","payload":{"playerId":"lat1","latency":80},"users":["lat1"]}`)
+
+	assert.Equal(t, int64(80), game.Players["lat1"].Latency)
+}
+
+func TestControlMessagesReachInstancesWithoutRoomPlayers(t *testing.T) {
+	elector := NewMockLeaderElector(t)
+	attempted := make(chan string, 1)
+	elector.On("AttemptLeadership", "handoffRoom").Run(func(args mock.Arguments) {
+		attempted <- args.String(0)
+	}).Return()
+	bus := NewMemoryMessageBus()
+	repo := NewGameStateRepository(elector, nil, bus)
+
+	repo.PublishControl(`{"type":"GAME_HANDOFF","payload":{"instance":"other","roomId":"handoffRoom"}}`)
+	select {
+	case roomId := <-attempted:
+		assert.Equal(t, "handoffRoom", roomId)
+	case <-time.After(time.Second):
+		t.Fatal("the handoff did not reach the instance")
+	}
+
+	repo.SendControlMessage(`{"type":"GAME_START_INFO","payload":{"instance":"` + instanceID + `","roomId":"ownRoom"}}`)
+	elector.AssertNotCalled(t, "AttemptLeadership", "ownRoom")
+}
+
+func TestRoomRemovalEventsReleaseLocalPlayers(t *testing.T) {
+	repo := NewGameStateRepository(nil, nil, NewMemoryMessageBus())
+	state.RegisterPlayer("kick1", "releaseRoom", nil, "")
+	state.RegisterPlayer("kick2", "releaseRoom", nil, "")
+	state.RegisterPlayer("kick3", "otherRoom", nil, "")
+	defer state.UnregisterPlayer("kick3")
+
+	repo.SendReceivedMessage(`{"type":"ROOM_KICK","payload":{"roomId":"releaseRoom","kicked":"kick1"},"users":["kick1","kick2"]}`)
+	assert.Nil(t, state.GetPlayer("kick1"))
+	assert.NotNil(t, state.GetPlayer("kick2"))
+
+	repo.SendReceivedMessage(`{"type":"ROOM_DELETED","payload":{"roomId":"releaseRoom"},"users":["kick2","kick3"]}`)
+	assert.Nil(t, state.GetPlayer("kick2"))
+	assert.NotNil(t, state.GetPlayer("kick3"))
+	assert.NotContains(t, state.GetLocalRooms(), "releaseRoom")
+}
+
+func TestGameStartLooksUpTheLeaderToRedirectPlayers(t *testing.T) {
+	leaders := NewMockGameStateRepository(t)
+	lookedUp := make(chan string, 1)
+	leaders.On("GetLeader", "startRoom").Run(func(args mock.Arguments) {
+		lookedUp <- args.String(0)
+	}).Return("", nil)
+	repo := NewGameStateRepository(nil, nil, NewMemoryMessageBus())
+	repo.SetLeaderLocator(NewLeaderLocator(leaders, NewMemoryInstanceRegistry()))
+
+	repo.SendReceivedMessage(`{"type":"GAME_START","payload":{},"users":["p1"],"room":"startRoom"}`)
+	select {
+	case roomId := <-lookedUp:
+		assert.Equal(t, "startRoom", roomId)
+	case <-time.After(time.Second):
+		t.Fatal("the game start did not look up the room leader")
+	}
+}
@@ -0,0 +1,223 @@
+package game
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+	"github.com/thesrcielos/TopTankBattle/pkg/db"
+)
+
+// heartbeatInterval is how often an instance refreshes its registry entry
+const heartbeatInterval = 5 * time.Second
+
+// instanceTimeout is how long an instance can miss heartbeats before it is reported dead
+const instanceTimeout = 3 * heartbeatInterval
+
+// instanceRetention keeps the entry of a dead instance visible to operators for a while
+const instanceRetention = 10 * time.Minute
+
+// InstanceInfo is the entry of a server instance in the registry
+type InstanceInfo struct {
+	ID            string    `json:"id"`
+	Address       string    `json:"address"`
+	Players       int       `json:"players"`
+	Rooms         []string  `json:"rooms"`
+	StartedAt     time.Time `json:"startedAt"`
+	LastHeartbeat time.Time `json:"lastHeartbeat"`
+	Draining      bool      `json:"draining"`
+	Alive         bool      `json:"alive"`
+}
+
+// InstanceRegistry keeps track of the instances of the cluster
+type InstanceRegistry interface {
+	Register(info InstanceInfo) error
+	Deregister(instanceId string) error
+	ListInstances() ([]InstanceInfo, error)
+	TryLock(name string, ttl time.Duration) (bool, error)
+}
+
+// InstanceID returns the id of this instance
+func InstanceID() string {
+	return instanceID
+}
+
+type RedisInstanceRegistry struct {
+	db *redis.Client
+}
+
+func NewRedisInstanceRegistry(db *redis.Client) *RedisInstanceRegistry {
+	return &RedisInstanceRegistry{db: db}
+}
+
+func (r *RedisInstanceRegistry) Register(info InstanceInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return apperrors.NewAppError(500, "Error serializing instance", err)
+	}
+
+	pipe := r.db.TxPipeline()
+	pipe.Set(ctx, db.InstanceKey(info.ID), data, instanceRetention)
+	pipe.SAdd(ctx, db.InstancesKey, info.ID)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return apperrors.NewAppError(500, "Error registering instance", err)
+	}
+	return nil
+}
+
+func (r *RedisInstanceRegistry) Deregister(instanceId string) error {
+	pipe := r.db.TxPipeline()
+	pipe.Del(ctx, db.InstanceKey(instanceId))
+	pipe.SRem(ctx, db.InstancesKey, instanceId)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return apperrors.NewAppError(500, "Error deregistering instance", err)
+	}
+	return nil
+}
+
+// lockScript takes or extends a lock for this instance, it fails while another instance holds it
+var lockScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner and owner ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+return 1
+`)
+
+// TryLock makes this instance the only one running a cluster wide task for ttl. The owner
+// keeps the lock every time it takes it again, other instances get it once it expires
+func (r *RedisInstanceRegistry) TryLock(name string, ttl time.Duration) (bool, error) {
+	locked, err := lockScript.Run(ctx, r.db, []string{db.LockKey(name)}, instanceID, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, apperrors.NewAppError(500, "Error taking lock", err)
+	}
+	return locked == 1, nil
+}
+
+// ListInstances returns the registered instances, forgetting the ones whose entry expired
+func (r *RedisInstanceRegistry) ListInstances() ([]InstanceInfo, error) {
+	ids, err := r.db.SMembers(ctx, db.InstancesKey).Result()
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error listing instances", err)
+	}
+
+	instances := []InstanceInfo{}
+	if len(ids) == 0 {
+		return instances, nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, db.InstanceKey(id))
+	}
+	values, err := r.db.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error listing instances", err)
+	}
+
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			r.db.SRem(ctx, db.InstancesKey, ids[i])
+			continue
+		}
+		var info InstanceInfo
+		if err := json.Unmarshal([]byte(data), &info); err != nil {
+			log.Println("Error decoding instance:", err)
+			continue
+		}
+		instances = append(instances, info)
+	}
+	return withLiveness(instances, time.Now()), nil
+}
+
+// withLiveness marks the instances that sent a heartbeat recently and sorts them by id
+func withLiveness(instances []InstanceInfo, now time.Time) []InstanceInfo {
+	for i := range instances {
+		instances[i].Alive = now.Sub(instances[i].LastHeartbeat) < instanceTimeout
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+	return instances
+}
+
+// Heartbeat keeps the registry entry of this instance up to date
+type Heartbeat struct {
+	registry    InstanceRegistry
+	gameService *GameServiceImpl
+	address     string
+	startedAt   time.Time
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func NewHeartbeat(registry InstanceRegistry, gameService *GameServiceImpl) *Heartbeat {
+	return &Heartbeat{
+		registry:    registry,
+		gameService: gameService,
+		address:     advertisedAddress(),
+		startedAt:   time.Now(),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// advertisedAddress is the address where the clients can reach this instance, set with ADVERTISED_ADDRESS
+func advertisedAddress() string {
+	if address := os.Getenv("ADVERTISED_ADDRESS"); address != "" {
+		return address
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return hostname + ":8080"
+}
+
+// Start registers the instance and refreshes its entry every heartbeatInterval
+func (h *Heartbeat) Start() {
+	h.beat()
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				h.beat()
+			}
+		}
+	}()
+}
+
+// Stop removes the instance from the registry once the last beat is done, so it is not registered again
+func (h *Heartbeat) Stop() {
+	close(h.stop)
+	<-h.done
+	if err := h.registry.Deregister(instanceID); err != nil {
+		log.Println("Error deregistering instance:", err)
+	}
+}
+
+func (h *Heartbeat) beat() {
+	err := h.registry.Register(InstanceInfo{
+		ID:            instanceID,
+		Address:       h.address,
+		Players:       len(state.GetAllPlayers()),
+		Rooms:         h.gameService.LedRooms(),
+		StartedAt:     h.startedAt,
+		LastHeartbeat: time.Now(),
+		Draining:      h.gameService.IsDraining(),
+	})
+	if err != nil {
+		log.Println("Error sending heartbeat:", err)
+	}
+}
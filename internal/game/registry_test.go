@@ -0,0 +1,38 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryInstanceRegistryReportsDeadInstances(t *testing.T) {
+	registry := NewMemoryInstanceRegistry()
+	registry.Register(InstanceInfo{ID: "b", LastHeartbeat: time.Now()})
+	registry.Register(InstanceInfo{ID: "a", LastHeartbeat: time.Now().Add(-2 * instanceTimeout)})
+
+	instances, err := registry.ListInstances()
+	assert.NoError(t, err)
+	assert.Len(t, instances, 2)
+	assert.Equal(t, "a", instances[0].ID)
+	assert.False(t, instances[0].Alive)
+	assert.True(t, instances[1].Alive)
+
+	registry.Deregister("a")
+	instances, _ = registry.ListInstances()
+	assert.Len(t, instances, 1)
+}
+
+func TestHeartbeatStopDeregistersTheInstance(t *testing.T) {
+	registry := NewMemoryInstanceRegistry()
+	heartbeat := NewHeartbeat(registry, NewGameService(nil, nil, nil, nil))
+
+	heartbeat.Start()
+	instances, _ := registry.ListInstances()
+	assert.Len(t, instances, 1)
+
+	heartbeat.Stop()
+	instances, _ = registry.ListInstances()
+	assert.Empty(t, instances)
+}
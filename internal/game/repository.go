@@ -13,19 +13,31 @@ import (
 	"github.com/redis/go-redis/v9"
 	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/user"
+	"github.com/thesrcielos/TopTankBattle/pkg/db"
 )
 
 var ctx = context.Background()
 
+// roomEventsRetention is how long the events stream of a deleted room is kept
+const roomEventsRetention = time.Minute
+
+// ErrRoomConflict is returned when a room was modified since it was read, the update can be retried
+var ErrRoomConflict = apperrors.NewAppError(409, "Room was modified by another request", errors.New("room version conflict"))
+
+// ErrPlayerNotInRoom is returned when removing a player that already left the room
+var ErrPlayerNotInRoom = apperrors.NewAppError(404, "Player not found in room", errors.New("player not in room"))
+
 type RedisRoomRepository struct {
 	userRepository user.UserRepository
 	db             *redis.Client
+	bus            MessageBus
 }
 
-func NewRedisRoomRepository(userRepo user.UserRepository, db *redis.Client) *RedisRoomRepository {
+func NewRedisRoomRepository(userRepo user.UserRepository, db *redis.Client, bus MessageBus) *RedisRoomRepository {
 	return &RedisRoomRepository{
 		userRepository: userRepo,
 		db:             db,
+		bus:            bus,
 	}
 }
 
@@ -42,7 +54,10 @@ type RoomRepository interface {
 	RemovePlayer(*PlayerRequest) (*Room, error)
 	ChangeRoomOwner(roomId string, player Player) (*Room, error)
 	DeleteRoom(id string) error
-	PublishToRoom(payload string)
+	DeleteLobbyRoom(room *Room) error
+	PublishToRoom(roomId string, payload string)
+	GetRoomIDs() ([]string, error)
+	GetPlayerRooms() (map[string]string, error)
 }
 
 func (r *RedisRoomRepository) SaveRoomRequest(RoomRequest *RoomRequest) (*Room, error) {
@@ -68,28 +83,58 @@ func (r *RedisRoomRepository) SaveRoomRequest(RoomRequest *RoomRequest) (*Room,
 	}
 
 	timestamp := float64(time.Now().Unix())
-	if err := r.db.ZAdd(ctx, "rooms_id", redis.Z{Score: timestamp, Member: room.ID}).Err(); err != nil {
+	if err := r.db.ZAdd(ctx, db.RoomsKey, redis.Z{Score: timestamp, Member: room.ID}).Err(); err != nil {
 		return nil, apperrors.NewAppError(500, "Error saving room ID", err)
 	}
 
 	return room, nil
 }
 
+// saveRoomScript writes a room only if the stored version is still the one it was read with.
+// It returns -1 when the room was deleted and 0 on a version conflict.
+var saveRoomScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	if (cjson.decode(current)['version'] or 0) ~= tonumber(ARGV[1]) then
+		return 0
+	end
+elseif tonumber(ARGV[1]) ~= 0 then
+	return -1
+end
+redis.call('SET', KEYS[1], ARGV[2])
+return 1
+`)
+
+// SaveRoom stores a room and increases its version. It fails with ErrRoomConflict if the room
+// was saved by someone else since it was read.
 func (r *RedisRoomRepository) SaveRoom(room *Room) error {
-	data, err := json.Marshal(room)
+	next := *room
+	next.Version++
+	data, err := json.Marshal(next)
 	if err != nil {
 		return apperrors.NewAppError(500, "Error serializing room data", err)
 	}
 
-	if err := r.db.Set(ctx, room.ID, data, 0).Err(); err != nil {
+	saved, err := saveRoomScript.Run(ctx, r.db, []string{db.RoomKey(room.ID)}, room.Version, data).Int()
+	if err != nil {
 		return apperrors.NewAppError(500, "Error saving room", err)
 	}
+	if saved == -1 {
+		return apperrors.NewAppError(404, "Room not found", errors.New("room not found"))
+	}
+	if saved == 0 {
+		return ErrRoomConflict
+	}
 
+	room.Version = next.Version
 	return nil
 }
 
 func (r *RedisRoomRepository) SavePlayerRoom(playerRequest *PlayerRequest) error {
-	if err := r.db.Set(ctx, playerRequest.Player, playerRequest.Room, 0).Err(); err != nil {
+	pipe := r.db.TxPipeline()
+	pipe.Set(ctx, db.PlayerRoomKey(playerRequest.Player), playerRequest.Room, 0)
+	pipe.SAdd(ctx, db.PlayerRoomsKey, playerRequest.Player)
+	if _, err := pipe.Exec(ctx); err != nil {
 		fmt.Println("Error saving player room:", err)
 		return apperrors.NewAppError(500, "Error saving player room", err)
 	}
@@ -98,7 +143,7 @@ func (r *RedisRoomRepository) SavePlayerRoom(playerRequest *PlayerRequest) error
 }
 
 func (r *RedisRoomRepository) GetPlayerRoom(playerId string) (interface{}, error) {
-	val, err := r.db.Get(ctx, playerId).Result()
+	val, err := r.db.Get(ctx, db.PlayerRoomKey(playerId)).Result()
 	if err == redis.Nil {
 		return nil, nil
 	} else if err != nil {
@@ -109,7 +154,10 @@ func (r *RedisRoomRepository) GetPlayerRoom(playerId string) (interface{}, error
 }
 
 func (r *RedisRoomRepository) DeletePlayerRoom(playerId string) error {
-	if err := r.db.Del(ctx, playerId).Err(); err != nil {
+	pipe := r.db.TxPipeline()
+	pipe.Del(ctx, db.PlayerRoomKey(playerId))
+	pipe.SRem(ctx, db.PlayerRoomsKey, playerId)
+	if _, err := pipe.Exec(ctx); err != nil {
 		return apperrors.NewAppError(500, "Error deleting player room", err)
 	}
 
@@ -117,7 +165,7 @@ func (r *RedisRoomRepository) DeletePlayerRoom(playerId string) error {
 }
 
 func (r *RedisRoomRepository) GetRoom(key string) (*Room, error) {
-	val, err := r.db.Get(ctx, key).Result()
+	val, err := r.db.Get(ctx, db.RoomKey(key)).Result()
 	if err == redis.Nil {
 		return nil, apperrors.NewAppError(404, "Room not found", errors.New("room not found"))
 	} else if err != nil {
@@ -135,7 +183,7 @@ func (r *RedisRoomRepository) GetRooms(page, pageSize int) (*[]Room, error) {
 	start := int64(page * pageSize)
 	end := start + int64(pageSize) - 1
 
-	roomIDs, err := r.db.ZRevRange(ctx, "rooms_id", start, end).Result()
+	roomIDs, err := r.db.ZRevRange(ctx, db.RoomsKey, start, end).Result()
 	if err != nil {
 		return nil, apperrors.NewAppError(500, "Error getting room IDs", err)
 	}
@@ -209,6 +257,9 @@ func (r *RedisRoomRepository) RemovePlayer(req *PlayerRequest) (*Room, error) {
 	if room.Players == 0 {
 		return nil, apperrors.NewAppError(400, "Room is empty", errors.New("room is empty"))
 	}
+	if !roomHasPlayer(room, req.Player) {
+		return nil, ErrPlayerNotInRoom
+	}
 
 	players1 := room.Team1
 	newPlayers := make([]Player, 0, len(players1))
@@ -227,6 +278,7 @@ func (r *RedisRoomRepository) RemovePlayer(req *PlayerRequest) (*Room, error) {
 		}
 	}
 	room.Team2 = newPlayers2
+	room.SetReady(req.Player, false)
 	room.Players -= 1
 	if err := r.SaveRoom(room); err != nil {
 		return nil, err
@@ -243,26 +295,100 @@ func (r *RedisRoomRepository) ChangeRoomOwner(roomId string, player Player) (*Ro
 	room.Host = player
 
 	if err := r.SaveRoom(room); err != nil {
-		return nil, apperrors.NewAppError(500, "Error saving room after changing owner", err)
+		return nil, err
 	}
 
 	return room, nil
 }
 
+// DeleteRoom removes a room. Its events stream expires a bit later, so the instances can
+// still read the last events published to the room
 func (r *RedisRoomRepository) DeleteRoom(id string) error {
-	if err := r.db.Del(ctx, id).Err(); err != nil {
+	pipe := r.db.TxPipeline()
+	pipe.Del(ctx, db.RoomKey(id), db.ChatKey(id))
+	pipe.Expire(ctx, roomChannel(id), roomEventsRetention)
+	if _, err := pipe.Exec(ctx); err != nil {
 		log.Println("Error deleting room ", err)
 		return apperrors.NewAppError(500, "Error deleting room", err)
 	}
-	if err := r.db.ZRem(ctx, "rooms_id", id).Err(); err != nil {
+	if err := r.db.ZRem(ctx, db.RoomsKey, id).Err(); err != nil {
 		log.Println("Error removing room id from list ", err)
 		return apperrors.NewAppError(500, "Error removing room ID from list", err)
 	}
 	return nil
 }
 
-func (r *RedisRoomRepository) PublishToRoom(payload string) {
-	if err := r.db.Publish(ctx, "messages", payload).Err(); err != nil {
+// deleteLobbyRoomScript deletes a room only if it is still in the lobby with the version it was
+// read with. It returns -1 when the room was already deleted and 0 on a conflict.
+var deleteLobbyRoomScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if not current then
+	return -1
+end
+local room = cjson.decode(current)
+if (room['version'] or 0) ~= tonumber(ARGV[1]) or room['status'] ~= 'LOBBY' then
+	return 0
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+redis.call('ZREM', KEYS[4], ARGV[3])
+return 1
+`)
+
+// DeleteLobbyRoom removes a room that is still in the lobby. It fails with ErrRoomConflict if the
+// room was saved by someone else since it was read.
+func (r *RedisRoomRepository) DeleteLobbyRoom(room *Room) error {
+	keys := []string{db.RoomKey(room.ID), db.ChatKey(room.ID), roomChannel(room.ID), db.RoomsKey}
+	deleted, err := deleteLobbyRoomScript.Run(ctx, r.db, keys, room.Version, roomEventsRetention.Milliseconds(), room.ID).Int()
+	if err != nil {
+		return apperrors.NewAppError(500, "Error deleting room", err)
+	}
+	if deleted == -1 {
+		return apperrors.NewAppError(404, "Room not found", errors.New("room not found"))
+	}
+	if deleted == 0 {
+		return ErrRoomConflict
+	}
+	return nil
+}
+
+func (r *RedisRoomRepository) PublishToRoom(roomId string, payload string) {
+	if err := r.bus.Publish(roomChannel(roomId), payload); err != nil {
 		log.Println("Error publishing to room updates channel:", err)
 	}
 }
+
+// GetRoomIDs returns the ids of every listed room, including the ones whose data is gone
+func (r *RedisRoomRepository) GetRoomIDs() ([]string, error) {
+	ids, err := r.db.ZRange(ctx, db.RoomsKey, 0, -1).Result()
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error getting room IDs", err)
+	}
+	return ids, nil
+}
+
+// GetPlayerRooms returns the room of every player with a room mapping
+func (r *RedisRoomRepository) GetPlayerRooms() (map[string]string, error) {
+	playerIds, err := r.db.SMembers(ctx, db.PlayerRoomsKey).Result()
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error getting player rooms", err)
+	}
+
+	playerRooms := make(map[string]string, len(playerIds))
+	if len(playerIds) == 0 {
+		return playerRooms, nil
+	}
+	keys := make([]string, 0, len(playerIds))
+	for _, playerId := range playerIds {
+		keys = append(keys, db.PlayerRoomKey(playerId))
+	}
+	rooms, err := r.db.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error getting player rooms", err)
+	}
+	for i, room := range rooms {
+		roomId, _ := room.(string)
+		playerRooms[playerIds[i]] = roomId
+	}
+	return playerRooms, nil
+}
@@ -0,0 +1,284 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+	"github.com/thesrcielos/TopTankBattle/pkg/db"
+	"github.com/thesrcielos/TopTankBattle/websocket/transport"
+)
+
+// roomEvents publishes and handles the room and control events of the bus. It is shared by
+// every GameStateRepository, which only differ in where leaderships and checkpoints are kept.
+type roomEvents struct {
+	LeaderElector LeaderElector
+	locator       *LeaderLocator
+	bus           MessageBus
+	fences        map[string]int64
+	fencesMu      sync.Mutex
+	rooms         map[string]int
+	roomsMu       sync.Mutex
+}
+
+func newRoomEvents(leaderElector LeaderElector, bus MessageBus) *roomEvents {
+	r := &roomEvents{
+		LeaderElector: leaderElector,
+		bus:           bus,
+		fences:        make(map[string]int64),
+		rooms:         make(map[string]int),
+	}
+	bus.SetRecoveryHandler(r.resyncRooms)
+	if err := bus.Subscribe(controlChannel, r.SendControlMessage); err != nil {
+		log.Println("Error subscribing to control events:", err)
+	}
+	return r
+}
+
+func (r *roomEvents) SetLeaderElector(elector LeaderElector) {
+	r.LeaderElector = elector
+}
+
+// SetLeaderLocator sets the locator used to redirect the players of a game led by another instance
+func (r *roomEvents) SetLeaderLocator(locator *LeaderLocator) {
+	r.locator = locator
+}
+
+// controlChannel is the topic every instance listens to
+const controlChannel = db.ControlEventsKey
+
+// roomChannel is the topic where the events of a room are published
+func roomChannel(roomID string) string {
+	return db.RoomEventsKey(roomID)
+}
+
+func (r *roomEvents) PublishToRoom(roomID string, payload string) {
+	if err := r.bus.Publish(roomChannel(roomID), payload); err != nil {
+		log.Println("Error publishing to room:", err)
+	}
+}
+
+// PublishControl sends an event to every instance, whether it has players in the room or not
+func (r *roomEvents) PublishControl(payload string) {
+	if err := r.bus.Publish(controlChannel, payload); err != nil {
+		log.Println("Error publishing control event:", err)
+	}
+}
+
+// SubscribeRoom starts listening to the events of a room. Subscriptions are counted so a room
+// followed by local players and by the game loop is only unsubscribed when both are done.
+func (r *roomEvents) SubscribeRoom(roomID string) error {
+	r.roomsMu.Lock()
+	defer r.roomsMu.Unlock()
+
+	r.rooms[roomID]++
+	if r.rooms[roomID] > 1 {
+		return nil
+	}
+
+	if err := r.bus.Subscribe(roomChannel(roomID), r.SendReceivedMessage); err != nil {
+		return fmt.Errorf("error subscribing to room %s: %w", roomID, err)
+	}
+	return nil
+}
+
+// UnsubscribeRoom stops listening to a room once nobody on this instance needs it
+func (r *roomEvents) UnsubscribeRoom(roomID string) error {
+	r.roomsMu.Lock()
+	defer r.roomsMu.Unlock()
+
+	if r.rooms[roomID] == 0 {
+		return nil
+	}
+	r.rooms[roomID]--
+	if r.rooms[roomID] > 0 {
+		return nil
+	}
+	delete(r.rooms, roomID)
+
+	r.fencesMu.Lock()
+	delete(r.fences, roomID)
+	r.fencesMu.Unlock()
+
+	if err := r.bus.Unsubscribe(roomChannel(roomID)); err != nil {
+		return fmt.Errorf("error unsubscribing from room %s: %w", roomID, err)
+	}
+	return nil
+}
+
+// resyncRooms asks the leader elector to resync every room followed by this instance after
+// the bus recovers from a disconnection
+func (r *roomEvents) resyncRooms() {
+	r.roomsMu.Lock()
+	rooms := make([]string, 0, len(r.rooms))
+	for roomID := range r.rooms {
+		rooms = append(rooms, roomID)
+	}
+	r.roomsMu.Unlock()
+
+	for _, roomID := range rooms {
+		log.Println("Resyncing room after bus recovery:", roomID)
+		r.LeaderElector.ResyncRoom(roomID)
+	}
+}
+
+func (r *roomEvents) SendReceivedMessage(messageEncoded string) {
+	var message GameMessage
+	if err := json.Unmarshal([]byte(messageEncoded), &message); err != nil {
+		log.Println("Error decoding message:", err)
+		return
+	}
+	fmt.Println("Received message:", message.Type, "for players:", message.Users)
+	if !r.acceptFence(message.Room, message.Fence) {
+		log.Println("Discarding message from stale leader:", message.Type, "room:", message.Room, "fence:", message.Fence)
+		return
+	}
+	if message.Type == "GAME_MOVE" {
+		payloadBytes, _ := json.Marshal(message.Payload)
+		var move MovePlayerMessage
+		json.Unmarshal(payloadBytes, &move)
+		r.UpdateGamePlayerState(move.PlayerId, move.Position, message.Users)
+		return
+	}
+	if message.Type == "GAME_SHOOT" {
+		payloadBytes, _ := json.Marshal(message.Payload)
+		var bullet state.Bullet
+		json.Unmarshal(payloadBytes, &bullet)
+		r.UpdateGameBullets(bullet, message.Users)
+		return
+	}
+	if message.Type == "LATENCY" {
+		payloadBytes, _ := json.Marshal(message.Payload)
+		var latency LatencyMessage
+		if err := json.Unmarshal(payloadBytes, &latency); err == nil {
+			r.updatePlayerLatency(latency, message.Users)
+		}
+	}
+	msg := transport.OutgoingMessage{
+		Type:    message.Type,
+		Payload: message.Payload,
+	}
+
+	for _, playerId := range message.Users {
+		transport.SendToPlayer(playerId, msg)
+	}
+	r.releaseRemovedPlayers(message)
+	if message.Type == "GAME_START" && r.locator != nil {
+		go r.locator.Redirect(message.Room, message.Users)
+	}
+}
+
+// releaseRemovedPlayers lets go of the local connections of the players a room event took out
+// of their room, once the event is queued for them, so they stop acting on the room and the
+// instance stops following it when they were its last players here
+func (r *roomEvents) releaseRemovedPlayers(message GameMessage) {
+	payloadBytes, _ := json.Marshal(message.Payload)
+	switch message.Type {
+	case "ROOM_KICK":
+		var kick KickPlayerMessage
+		if err := json.Unmarshal(payloadBytes, &kick); err == nil {
+			state.ReleasePlayer(kick.Kicked, kick.Room)
+		}
+	case "ROOM_DELETED":
+		var deleted RoomDeletedMessage
+		if err := json.Unmarshal(payloadBytes, &deleted); err == nil {
+			for _, playerId := range message.Users {
+				state.ReleasePlayer(playerId, deleted.RoomId)
+			}
+		}
+	}
+}
+
+// SendControlMessage handles the events published to every instance: a game started or handed
+// off by another instance makes this one watch the room leader, so the game survives its leader
+// even when no other instance has players in the room
+func (r *roomEvents) SendControlMessage(messageEncoded string) {
+	var message GameMessage
+	if err := json.Unmarshal([]byte(messageEncoded), &message); err != nil {
+		log.Println("Error decoding control message:", err)
+		return
+	}
+	if message.Type != "GAME_START_INFO" && message.Type != "GAME_HANDOFF" {
+		log.Println("Unknown control message:", message.Type)
+		return
+	}
+
+	payloadBytes, _ := json.Marshal(message.Payload)
+	var info GameInfo
+	if err := json.Unmarshal(payloadBytes, &info); err != nil {
+		log.Println("Error decoding game info:", err)
+		return
+	}
+	if info.Instance == instanceID {
+		return
+	}
+	if message.Type == "GAME_HANDOFF" {
+		log.Printf("Instance %s handed off room %s, taking over", info.Instance, info.RoomId)
+	}
+	go r.LeaderElector.AttemptLeadership(info.RoomId)
+}
+
+// acceptFence tracks the highest fencing token seen for a room and rejects older ones
+func (r *roomEvents) acceptFence(roomID string, fence int64) bool {
+	if roomID == "" || fence == 0 {
+		return true
+	}
+
+	r.fencesMu.Lock()
+	defer r.fencesMu.Unlock()
+	if fence < r.fences[roomID] {
+		return false
+	}
+	r.fences[roomID] = fence
+	return true
+}
+
+func (r *roomEvents) UpdateGamePlayerState(playerId string, position state.Position, players []string) {
+	players = append(players, playerId)
+	for _, id := range players {
+		player := state.GetPlayer(id)
+		if player == nil || player.GameState == nil {
+			return
+		}
+		game := player.GameState
+		game.GameMu.Lock()
+		game.Players[playerId].Position = position
+		game.GameMu.Unlock()
+		break
+	}
+}
+
+func (r *roomEvents) UpdateGameBullets(bullet state.Bullet, players []string) {
+	players = append(players, bullet.OwnerId)
+	for _, playerId := range players {
+		player := state.GetPlayer(playerId)
+		if player == nil || player.GameState == nil {
+			continue
+		}
+		game := player.GameState
+		game.GameMu.Lock()
+		game.AddBullet(&bullet)
+		game.GameMu.Unlock()
+		return
+	}
+
+}
+
+// updatePlayerLatency stores the latency of a player in the game led by this instance
+func (r *roomEvents) updatePlayerLatency(latency LatencyMessage, players []string) {
+	for _, playerId := range players {
+		player := state.GetPlayer(playerId)
+		if player == nil || player.GameState == nil {
+			continue
+		}
+		game := player.GameState
+		game.GameMu.Lock()
+		if playerState, ok := game.Players[latency.PlayerId]; ok {
+			playerState.Latency = latency.Latency
+		}
+		game.GameMu.Unlock()
+		return
+	}
+}
@@ -2,13 +2,18 @@ package game
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"math"
 	"strconv"
 
 	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
 )
 
+// roomUpdateRetries is how many times a room update is attempted when it keeps conflicting
+const roomUpdateRetries = 5
+
 type RoomService struct {
 	repo RoomRepository
 }
@@ -62,7 +67,9 @@ func (r *RoomService) JoinRoom(playerRequest *PlayerRequest) (*Room, error) {
 	if val != nil {
 		return nil, apperrors.NewAppError(400, "Player already in a room", nil)
 	}
-	room, err := r.repo.AddPlayer(playerRequest)
+	room, err := retryOnConflict(func() (*Room, error) {
+		return r.repo.AddPlayer(playerRequest)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -92,8 +99,17 @@ func (r *RoomService) LeaveRoom(playerId string) error {
 		Room:   val.(string),
 	}
 
-	room, err := r.repo.RemovePlayer(playerRequest)
-	if err != nil {
+	room, err := retryOnConflict(func() (*Room, error) {
+		return r.repo.RemovePlayer(playerRequest)
+	})
+	if errors.Is(err, ErrPlayerNotInRoom) {
+		// A concurrent kick or leave already took the player out of the room
+		if err := r.repo.DeletePlayerRoom(playerId); err != nil {
+			return err
+		}
+		state.UnregisterPlayer(playerId)
+		return nil
+	} else if err != nil {
 		return err
 	}
 
@@ -113,6 +129,175 @@ func (r *RoomService) LeaveRoom(playerId string) error {
 	return nil
 }
 
+// GetPlayerRoom returns the id of the room the player is in
+func (r *RoomService) GetPlayerRoom(playerId string) (string, error) {
+	val, errDB := r.repo.GetPlayerRoom(playerId)
+	if errDB != nil {
+		return "", errDB
+	}
+	if val == nil {
+		return "", apperrors.NewAppError(404, "User room not found", nil)
+	}
+	return val.(string), nil
+}
+
+// KickPlayer removes a player from the room of the host
+func (r *RoomService) KickPlayer(hostId string, roomId string, playerId string) error {
+	room, err := retryOnConflict(func() (*Room, error) {
+		room, err := r.repo.GetRoom(roomId)
+		if err != nil {
+			return nil, err
+		}
+		if room.Host.ID != hostId {
+			return nil, apperrors.NewAppError(403, "Only the host can kick players", nil)
+		}
+		if room.Status != "LOBBY" {
+			return nil, apperrors.NewAppError(400, "Cannot kick players during a game", nil)
+		}
+		if playerId == hostId {
+			return nil, apperrors.NewAppError(400, "The host cannot kick itself", nil)
+		}
+		if _, err := r.getPlayerFromRoom(playerId, room); err != nil {
+			return nil, err
+		}
+
+		room.Team1 = withoutPlayer(room.Team1, playerId)
+		room.Team2 = withoutPlayer(room.Team2, playerId)
+		room.SetReady(playerId, false)
+		room.Players -= 1
+		return room, r.repo.SaveRoom(room)
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.repo.DeletePlayerRoom(playerId); err != nil {
+		return err
+	}
+
+	r.sendRoomChangeMessage(room, GameMessage{
+		Type: "ROOM_KICK",
+		Payload: KickPlayerMessage{
+			Room:   roomId,
+			Kicked: playerId,
+		},
+	}, playerId)
+	return nil
+}
+
+// SwitchTeam moves a player to the other team of its room, as long as the teams stay balanced
+func (r *RoomService) SwitchTeam(playerId string) (*Room, error) {
+	roomId, err := r.GetPlayerRoom(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	var switched TeamSwitchMessage
+	room, err := retryOnConflict(func() (*Room, error) {
+		room, err := r.repo.GetRoom(roomId)
+		if err != nil {
+			return nil, err
+		}
+		if room.Status != "LOBBY" {
+			return nil, apperrors.NewAppError(400, "Cannot switch teams during a game", nil)
+		}
+		player, err := r.getPlayerFromRoom(playerId, room)
+		if err != nil {
+			return nil, err
+		}
+
+		switched = TeamSwitchMessage{Player: player["player"].(Player)}
+		if player["team"].(int) == 1 {
+			room.Team1 = withoutPlayer(room.Team1, playerId)
+			room.Team2 = append(room.Team2, switched.Player)
+			switched.Team = 2
+		} else {
+			room.Team2 = withoutPlayer(room.Team2, playerId)
+			room.Team1 = append(room.Team1, switched.Player)
+			switched.Team = 1
+		}
+		if math.Abs(float64(len(room.Team1)-len(room.Team2))) > 1 {
+			return nil, apperrors.NewAppError(400, "Cannot switch teams: teams must have at most 1 player more than the other team", nil)
+		}
+		room.SetReady(playerId, false)
+		return room, r.repo.SaveRoom(room)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.sendRoomChangeMessage(room, GameMessage{
+		Type:    "ROOM_TEAM_SWITCH",
+		Payload: switched,
+	})
+	return room, nil
+}
+
+// SetReady marks a player of a room in the lobby as ready or not ready to start
+func (r *RoomService) SetReady(playerId string, ready bool) (*Room, error) {
+	roomId, err := r.GetPlayerRoom(playerId)
+	if err != nil {
+		return nil, err
+	}
+
+	room, err := retryOnConflict(func() (*Room, error) {
+		room, err := r.repo.GetRoom(roomId)
+		if err != nil {
+			return nil, err
+		}
+		if room.Status != "LOBBY" {
+			return nil, apperrors.NewAppError(400, "Cannot change readiness during a game", nil)
+		}
+		if _, err := r.getPlayerFromRoom(playerId, room); err != nil {
+			return nil, err
+		}
+		room.SetReady(playerId, ready)
+		return room, r.repo.SaveRoom(room)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.sendRoomChangeMessage(room, GameMessage{
+		Type: "ROOM_READY",
+		Payload: ReadyMessage{
+			Player: playerId,
+			Ready:  ready,
+		},
+	})
+	return room, nil
+}
+
+// DeleteRoom lets the host close its room, the players in it are notified and released
+func (r *RoomService) DeleteRoom(hostId string, roomId string) error {
+	room, err := retryOnConflict(func() (*Room, error) {
+		room, err := r.repo.GetRoom(roomId)
+		if err != nil {
+			return nil, err
+		}
+		if room.Host.ID != hostId {
+			return nil, apperrors.NewAppError(403, "Only the host can delete the room", nil)
+		}
+		if room.Status != "LOBBY" {
+			return nil, apperrors.NewAppError(400, "Cannot delete a room during a game", nil)
+		}
+		return room, r.repo.DeleteLobbyRoom(room)
+	})
+	if err != nil {
+		return err
+	}
+
+	r.sendRoomChangeMessage(room, GameMessage{
+		Type:    "ROOM_DELETED",
+		Payload: RoomDeletedMessage{RoomId: roomId},
+	})
+	for _, player := range append(append([]Player{}, room.Team1...), room.Team2...) {
+		if err := r.repo.DeletePlayerRoom(player.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // notifyPlayerJoin notifies when a player joins a room
 func (r *RoomService) notifyPlayerJoin(room *Room, playerId string) error {
 	player, err := r.getPlayerFromRoom(playerId, room)
@@ -132,9 +317,11 @@ func (r *RoomService) notifyPlayerJoin(room *Room, playerId string) error {
 	return nil
 }
 
-// sendRoomChangeMessage notifies when there is a change in a room
-func (r *RoomService) sendRoomChangeMessage(room *Room, message GameMessage) {
-	players := make([]string, 0, len(room.Team1)+len(room.Team2))
+// sendRoomChangeMessage notifies when there is a change in a room, to its players and to the
+// extra ones that are no longer in it
+func (r *RoomService) sendRoomChangeMessage(room *Room, message GameMessage, extra ...string) {
+	players := make([]string, 0, len(room.Team1)+len(room.Team2)+len(extra))
+	players = append(players, extra...)
 	for _, player := range room.Team1 {
 		players = append(players, player.ID)
 	}
@@ -147,7 +334,7 @@ func (r *RoomService) sendRoomChangeMessage(room *Room, message GameMessage) {
 		log.Println("Error encoding message:", err)
 		return
 	}
-	r.repo.PublishToRoom(string(msg))
+	r.repo.PublishToRoom(room.ID, string(msg))
 }
 
 // getPlayerFromRoom get the Player from a room
@@ -189,6 +376,19 @@ func (r *RoomService) notifyOrDeleteRoom(room *Room, playerId string) error {
 	return nil
 }
 
+// retryOnConflict repeats a room update that lost the race against a concurrent one
+func retryOnConflict(update func() (*Room, error)) (*Room, error) {
+	var room *Room
+	var err error
+	for attempt := 0; attempt < roomUpdateRetries; attempt++ {
+		room, err = update()
+		if !errors.Is(err, ErrRoomConflict) {
+			return room, err
+		}
+	}
+	return nil, err
+}
+
 // changeOwnerIfNeeded change the room owner if the host leaves the room
 func (r *RoomService) changeOwnerIfNeeded(playerID string, room *Room) error {
 	if room.Host.ID != playerID || room.Players == 0 {
@@ -200,7 +400,9 @@ func (r *RoomService) changeOwnerIfNeeded(playerID string, room *Room) error {
 	} else {
 		newHost = room.Team2[0]
 	}
-	_, err := r.repo.ChangeRoomOwner(room.ID, newHost)
+	_, err := retryOnConflict(func() (*Room, error) {
+		return r.repo.ChangeRoomOwner(room.ID, newHost)
+	})
 	if err != nil {
 		return err
 	}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/thesrcielos/TopTankBattle/internal/user"
 )
 
 func newTestRoomService(t *testing.T) (*RoomService, *MockRoomRepository) {
@@ -46,7 +47,7 @@ func TestRoomServiceJoinRoomSuccess(t *testing.T) {
 	roomJoin := &Room{ID: "room1", Host: Player{ID: "1"}, Team1: []Player{{ID: "1"}}, Team2: []Player{{ID: "2"}}, Players: 2}
 	mockRepo.On("AddPlayer", playerReq).Return(roomJoin, nil)
 	mockRepo.On("SavePlayerRoom", playerReq).Return(nil)
-	mockRepo.On("PublishToRoom", mock.Anything).Return()
+	mockRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 
 	result, err := rs.JoinRoom(playerReq)
 	assert.NoError(t, err)
@@ -73,7 +74,7 @@ func TestRoomServiceLeaveRoomSuccess(t *testing.T) {
 	room := &Room{ID: "room1", Host: Player{ID: "1"}, Team1: []Player{{ID: "1"}}, Team2: []Player{}, Players: 1}
 	mockRepo.On("RemovePlayer", playerReq).Return(room, nil)
 	mockRepo.On("DeletePlayerRoom", "2").Return(nil)
-	mockRepo.On("PublishToRoom", mock.Anything).Return()
+	mockRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
 
 	err := rs.LeaveRoom("2")
 	assert.NoError(t, err)
@@ -180,3 +181,179 @@ func TestRoomServiceGetPlayerFromRoom(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, result)
 }
+
+func TestRoomServiceJoinRoomRetriesOnConflict(t *testing.T) {
+	rs, mockRepo := newTestRoomService(t)
+	playerReq := &PlayerRequest{Player: "2", Room: "room1"}
+	mockRepo.On("GetPlayerRoom", "2").Return(nil, nil)
+	roomJoin := &Room{ID: "room1", Host: Player{ID: "1"}, Team1: []Player{{ID: "1"}}, Team2: []Player{{ID: "2"}}, Players: 2}
+	mockRepo.On("AddPlayer", playerReq).Return(nil, ErrRoomConflict).Once()
+	mockRepo.On("AddPlayer", playerReq).Return(roomJoin, nil).Once()
+	mockRepo.On("SavePlayerRoom", playerReq).Return(nil)
+	mockRepo.On("PublishToRoom", mock.Anything, mock.Anything).Return()
+
+	result, err := rs.JoinRoom(playerReq)
+	assert.NoError(t, err)
+	assert.Equal(t, roomJoin, result)
+	mockRepo.AssertNumberOfCalls(t, "AddPlayer", 2)
+}
+
+func TestRoomServiceJoinRoomGivesUpAfterRepeatedConflicts(t *testing.T) {
+	rs, mockRepo := newTestRoomService(t)
+	playerReq := &PlayerRequest{Player: "2", Room: "room1"}
+	mockRepo.On("GetPlayerRoom", "2").Return(nil, nil)
+	mockRepo.On("AddPlayer", playerReq).Return(nil, ErrRoomConflict)
+
+	result, err := rs.JoinRoom(playerReq)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrRoomConflict)
+	mockRepo.AssertNumberOfCalls(t, "AddPlayer", roomUpdateRetries)
+}
+
+// newLobby creates a room in memory hosted by player 1 with players 2 and 3 joined
+func newLobby(t *testing.T) (*RoomService, *MemoryRoomRepository, *Room) {
+	userRepo := user.NewMockUserRepository(t)
+	userRepo.On("GetUserUsername", mock.Anything).Return("player", nil)
+	repo := NewMemoryRoomRepository(userRepo, NewMemoryMessageBus())
+	rs := NewRoomService(repo)
+
+	room, err := rs.CreateRoom(&RoomRequest{Name: "lobby", Player: 1, Capacity: 4})
+	assert.NoError(t, err)
+	for _, id := range []string{"2", "3"} {
+		_, err := rs.JoinRoom(&PlayerRequest{Player: id, Room: room.ID})
+		assert.NoError(t, err)
+	}
+	room, _ = repo.GetRoom(room.ID)
+	return rs, repo, room
+}
+
+func TestRoomServiceKickPlayer(t *testing.T) {
+	rs, repo, room := newLobby(t)
+
+	err := rs.KickPlayer("2", room.ID, "3")
+	assert.EqualError(t, err, "Only the host can kick players")
+	err = rs.KickPlayer("1", room.ID, "1")
+	assert.EqualError(t, err, "The host cannot kick itself")
+
+	assert.NoError(t, rs.KickPlayer("1", room.ID, "3"))
+	room, _ = repo.GetRoom(room.ID)
+	assert.Equal(t, 2, room.Players)
+	playerRoom, _ := repo.GetPlayerRoom("3")
+	assert.Nil(t, playerRoom)
+
+	err = rs.KickPlayer("1", room.ID, "3")
+	assert.EqualError(t, err, "Player not found in room")
+}
+
+// racingRoomRepository runs race right before the first room write, like a concurrent request
+type racingRoomRepository struct {
+	*MemoryRoomRepository
+	race func()
+}
+
+func (r *racingRoomRepository) runRace() {
+	if race := r.race; race != nil {
+		r.race = nil
+		race()
+	}
+}
+
+func (r *racingRoomRepository) SaveRoom(room *Room) error {
+	r.runRace()
+	return r.MemoryRoomRepository.SaveRoom(room)
+}
+
+func (r *racingRoomRepository) DeleteLobbyRoom(room *Room) error {
+	r.runRace()
+	return r.MemoryRoomRepository.DeleteLobbyRoom(room)
+}
+
+func TestRoomServiceKickPlayerRechecksRoomOnConflict(t *testing.T) {
+	_, repo, room := newLobby(t)
+	racing := &racingRoomRepository{MemoryRoomRepository: repo, race: func() {
+		started, _ := repo.GetRoom(room.ID)
+		started.Status = "PLAYING"
+		repo.SaveRoom(started)
+	}}
+
+	err := NewRoomService(racing).KickPlayer("1", room.ID, "3")
+	assert.EqualError(t, err, "Cannot kick players during a game")
+	room, _ = repo.GetRoom(room.ID)
+	assert.Equal(t, 3, room.Players)
+}
+
+func TestRoomServiceDeleteRoomRechecksHostOnConflict(t *testing.T) {
+	_, repo, room := newLobby(t)
+	racing := &racingRoomRepository{MemoryRoomRepository: repo, race: func() {
+		repo.ChangeRoomOwner(room.ID, Player{ID: "2"})
+	}}
+
+	err := NewRoomService(racing).DeleteRoom("1", room.ID)
+	assert.EqualError(t, err, "Only the host can delete the room")
+	_, err = repo.GetRoom(room.ID)
+	assert.NoError(t, err)
+}
+
+func TestRoomServiceSwitchTeamKeepsTeamsBalanced(t *testing.T) {
+	rs, _, room := newLobby(t)
+	// Team1: 1, 3 and Team2: 2
+	assert.Len(t, room.Team1, 2)
+
+	_, err := rs.SwitchTeam("2")
+	assert.EqualError(t, err, "Cannot switch teams: teams must have at most 1 player more than the other team")
+
+	room, err = rs.SwitchTeam("3")
+	assert.NoError(t, err)
+	assert.Len(t, room.Team1, 1)
+	assert.Len(t, room.Team2, 2)
+	assert.Equal(t, "3", room.Team2[1].ID)
+}
+
+func TestRoomServiceSetReady(t *testing.T) {
+	rs, repo, room := newLobby(t)
+
+	_, err := rs.SetReady("2", true)
+	assert.NoError(t, err)
+	room, _ = repo.GetRoom(room.ID)
+	assert.True(t, room.IsReady("2"))
+
+	_, err = rs.SwitchTeam("3")
+	assert.NoError(t, err)
+	_, err = rs.SetReady("2", false)
+	assert.NoError(t, err)
+	room, _ = repo.GetRoom(room.ID)
+	assert.Empty(t, room.Ready)
+
+	room.Status = "PLAYING"
+	assert.NoError(t, repo.SaveRoom(room))
+	_, err = rs.SetReady("2", true)
+	assert.EqualError(t, err, "Cannot change readiness during a game")
+}
+
+func TestRoomServiceDeleteRoom(t *testing.T) {
+	rs, repo, room := newLobby(t)
+
+	assert.EqualError(t, rs.DeleteRoom("2", room.ID), "Only the host can delete the room")
+	assert.NoError(t, rs.DeleteRoom("1", room.ID))
+
+	_, err := repo.GetRoom(room.ID)
+	assert.Error(t, err)
+	playerRooms, _ := repo.GetPlayerRooms()
+	assert.Empty(t, playerRooms)
+}
+
+func TestRoomServiceLeaveRoomAfterKickDoesNotRemoveTwice(t *testing.T) {
+	rs, repo, room := newLobby(t)
+	repo.SavePlayerRoom(&PlayerRequest{Player: "3", Room: room.ID})
+
+	_, err := repo.RemovePlayer(&PlayerRequest{Player: "3", Room: room.ID})
+	assert.NoError(t, err)
+	_, err = repo.RemovePlayer(&PlayerRequest{Player: "3", Room: room.ID})
+	assert.ErrorIs(t, err, ErrPlayerNotInRoom)
+
+	assert.NoError(t, rs.LeaveRoom("3"))
+	room, _ = repo.GetRoom(room.ID)
+	assert.Equal(t, 2, room.Players)
+	playerRoom, _ := repo.GetPlayerRoom("3")
+	assert.Nil(t, playerRoom)
+}
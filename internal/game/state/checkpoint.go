@@ -0,0 +1,119 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CheckpointVersion is the version of the serialized game state format.
+// Bump it whenever a field is added or its meaning changes.
+const CheckpointVersion = 1
+
+// Checkpoint is a complete snapshot of a GameState that a new leader can resume from
+type Checkpoint struct {
+	Version    int                     `json:"version"`
+	RoomId     string                  `json:"roomId"`
+	Timestamp  int64                   `json:"timestamp"`
+	Tick       int64                   `json:"tick"`
+	SavedAt    int64                   `json:"savedAt"`
+	Players    map[string]*PlayerState `json:"players"`
+	Bullets    map[string]*Bullet      `json:"bullets"`
+	Fortresses []*Fortress             `json:"fortresses"`
+	Respawns   map[string]int64        `json:"respawns"`
+}
+
+// NewCheckpoint copies the game state into a checkpoint. The caller must hold GameMu.
+// The latency of the players is kept so a new leader keeps compensating their lag.
+func NewCheckpoint(game *GameState) *Checkpoint {
+	checkpoint := &Checkpoint{
+		Version:    CheckpointVersion,
+		RoomId:     game.RoomId,
+		Timestamp:  game.Timestamp,
+		Tick:       game.Tick,
+		SavedAt:    time.Now().UnixMilli(),
+		Players:    make(map[string]*PlayerState, len(game.Players)),
+		Bullets:    make(map[string]*Bullet, len(game.Bullets)),
+		Fortresses: make([]*Fortress, 0, len(game.Fortresses)),
+		Respawns:   make(map[string]int64, len(game.Respawns)),
+	}
+
+	for id, p := range game.Players {
+		p.PlayerMu.Lock()
+		checkpoint.Players[id] = &PlayerState{
+			ID:       p.ID,
+			Position: p.Position,
+			Health:   p.Health,
+			Team1:    p.Team1,
+			Latency:  p.Latency,
+		}
+		p.PlayerMu.Unlock()
+	}
+
+	for id, b := range game.Bullets {
+		bullet := *b
+		checkpoint.Bullets[id] = &bullet
+	}
+
+	for _, f := range game.Fortresses {
+		checkpoint.Fortresses = append(checkpoint.Fortresses, &Fortress{
+			ID:       f.ID,
+			Position: f.Position,
+			Health:   f.Health,
+			Team1:    f.Team1,
+		})
+	}
+
+	for id, at := range game.Respawns {
+		checkpoint.Respawns[id] = at
+	}
+
+	return checkpoint
+}
+
+// Encode serializes the checkpoint
+func (c *Checkpoint) Encode() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+// DecodeCheckpoint deserializes a checkpoint and verifies its version
+func DecodeCheckpoint(data []byte) (*Checkpoint, error) {
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("error decoding checkpoint: %w", err)
+	}
+
+	if checkpoint.Version != CheckpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d, expected %d", checkpoint.Version, CheckpointVersion)
+	}
+
+	return &checkpoint, nil
+}
+
+// GameState rebuilds the game state stored in the checkpoint
+func (c *Checkpoint) GameState() *GameState {
+	game := &GameState{
+		Timestamp:  c.Timestamp,
+		Tick:       c.Tick,
+		RoomId:     c.RoomId,
+		Players:    c.Players,
+		Bullets:    c.Bullets,
+		Fortresses: c.Fortresses,
+		Respawns:   c.Respawns,
+	}
+
+	if game.Players == nil {
+		game.Players = make(map[string]*PlayerState)
+	}
+	if game.Bullets == nil {
+		game.Bullets = make(map[string]*Bullet)
+	}
+	if game.Fortresses == nil {
+		game.Fortresses = make([]*Fortress, 0)
+	}
+	if game.Respawns == nil {
+		game.Respawns = make(map[string]int64)
+	}
+
+	return game
+}
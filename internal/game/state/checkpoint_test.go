@@ -0,0 +1,67 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	game := &GameState{
+		Timestamp: 100,
+		Tick:      42,
+		RoomId:    "room1",
+		Players: map[string]*PlayerState{
+			"p1": {ID: "p1", Position: Position{X: 10, Y: 20, Angle: 1}, Health: 60, Team1: true, Latency: 80},
+			"p2": {ID: "p2", Position: Position{X: 30, Y: 40}, Health: 0, Team1: false},
+		},
+		Bullets: map[string]*Bullet{
+			"b1": {ID: "b1", Position: Position{X: 5, Y: 6}, Speed: 500, OwnerId: "p1", Team1: true},
+		},
+		Fortresses: []*Fortress{
+			{ID: "1", Position: Position{X: 48, Y: 416}, Health: 480, Team1: true},
+			{ID: "2", Position: Position{X: 1936, Y: 416}, Health: 500, Team1: false},
+		},
+	}
+	game.ScheduleRespawn("p2", time.UnixMilli(5000))
+
+	data, err := NewCheckpoint(game).Encode()
+	require.NoError(t, err)
+
+	checkpoint, err := DecodeCheckpoint(data)
+	require.NoError(t, err)
+	restored := checkpoint.GameState()
+
+	assert.Equal(t, "room1", restored.RoomId)
+	assert.Equal(t, int64(42), restored.Tick)
+	assert.Equal(t, int64(100), restored.Timestamp)
+	assert.Equal(t, 60, restored.Players["p1"].Health)
+	assert.True(t, restored.Players["p1"].Team1)
+	assert.Equal(t, int64(80), restored.Players["p1"].Latency)
+	assert.Equal(t, Position{X: 10, Y: 20, Angle: 1}, restored.Players["p1"].Position)
+	assert.False(t, restored.Players["p2"].Team1)
+	assert.True(t, restored.Bullets["b1"].Team1)
+	assert.Equal(t, "p1", restored.Bullets["b1"].OwnerId)
+	assert.Equal(t, 480, restored.Fortresses[0].Health)
+	assert.Equal(t, int64(5000), restored.Respawns["p2"])
+}
+
+func TestDecodeCheckpointRejectsUnknownVersion(t *testing.T) {
+	_, err := DecodeCheckpoint([]byte(`{"version":999,"roomId":"room1"}`))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported checkpoint version")
+}
+
+func TestDueRespawns(t *testing.T) {
+	game := &GameState{}
+	now := time.Now()
+	game.ScheduleRespawn("p1", now.Add(-time.Second))
+	game.ScheduleRespawn("p2", now.Add(time.Minute))
+
+	due := game.DueRespawns(now)
+	assert.Equal(t, []string{"p1"}, due)
+	assert.Contains(t, game.Respawns, "p2")
+	assert.NotContains(t, game.Respawns, "p1")
+}
@@ -0,0 +1,171 @@
+package state
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// outboxSize is how many messages can wait to be written to a client
+const outboxSize = 256
+
+// writeWait is the time allowed to write a message to a client
+const writeWait = 5 * time.Second
+
+// frameWriter is the part of a websocket connection used by the outbox
+type frameWriter interface {
+	SetWriteDeadline(t time.Time) error
+	WriteMessage(messageType int, data []byte) error
+	Close() error
+}
+
+type outboundMessage struct {
+	key       string
+	frameType int
+	data      []byte
+}
+
+// outbox is the bounded queue of messages of a connection, written by its own goroutine
+type outbox struct {
+	conn    frameWriter
+	queue   []outboundMessage
+	closed  bool
+	closing bool
+	mu      sync.Mutex
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+func newOutbox(conn frameWriter) *outbox {
+	o := &outbox{
+		conn: conn,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	go o.run()
+	return o
+}
+
+// push queues a message, replacing the queued one with the same key so stale updates are never sent.
+// It returns false when the message can't be queued because the client is backed up or gone
+func (o *outbox) push(key string, frameType int, data []byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return false
+	}
+	if o.closing {
+		// The connection is released, later messages are not meant for it
+		return true
+	}
+
+	if key != "" {
+		for i := range o.queue {
+			if o.queue[i].key == key {
+				o.queue[i].frameType = frameType
+				o.queue[i].data = data
+				return true
+			}
+		}
+	}
+	if len(o.queue) >= outboxSize && !o.dropStale() {
+		return false
+	}
+	o.queue = append(o.queue, outboundMessage{key: key, frameType: frameType, data: data})
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// dropStale makes room by discarding the oldest message that a newer one can replace
+func (o *outbox) dropStale() bool {
+	for i := range o.queue {
+		if o.queue[i].key != "" {
+			o.queue = append(o.queue[:i], o.queue[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (o *outbox) pop() (outboundMessage, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed || len(o.queue) == 0 {
+		return outboundMessage{}, false
+	}
+	msg := o.queue[0]
+	o.queue = o.queue[1:]
+	return msg, true
+}
+
+func (o *outbox) run() {
+	for {
+		select {
+		case <-o.done:
+			return
+		case <-o.wake:
+		}
+
+		for {
+			msg, ok := o.pop()
+			if !ok {
+				break
+			}
+			o.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := o.conn.WriteMessage(msg.frameType, msg.data); err != nil {
+				log.Println("Error writing message:", err)
+				o.close()
+				return
+			}
+		}
+		if o.finished() {
+			o.close()
+			return
+		}
+	}
+}
+
+// finish closes the connection once the queued messages are written
+func (o *outbox) finish() {
+	o.mu.Lock()
+	o.closing = true
+	o.mu.Unlock()
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// finished reports if the outbox was finished and has nothing left to write
+func (o *outbox) finished() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.closing && len(o.queue) == 0
+}
+
+// stop ends the writer goroutine, leaving the connection open
+func (o *outbox) stop() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return false
+	}
+	o.closed = true
+	o.queue = nil
+	close(o.done)
+	return true
+}
+
+// close ends the writer goroutine and closes the connection, so its reader handles the disconnection
+func (o *outbox) close() bool {
+	if !o.stop() {
+		return false
+	}
+	o.conn.Close()
+	return true
+}
@@ -0,0 +1,153 @@
+package state
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeConn struct {
+	mu        sync.Mutex
+	written   []string
+	deadlines int
+	closed    bool
+	block     chan struct{}
+	err       error
+}
+
+func (c *fakeConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadlines++
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *fakeConn) WriteMessage(messageType int, data []byte) error {
+	if c.block != nil {
+		<-c.block
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.written = append(c.written, string(data))
+	return nil
+}
+
+func (c *fakeConn) Close() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *fakeConn) snapshot() ([]string, int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string{}, c.written...), c.deadlines, c.closed
+}
+
+func TestOutboxWritesInOrderWithDeadlines(t *testing.T) {
+	conn := &fakeConn{}
+	out := newOutbox(conn)
+	defer out.stop()
+
+	assert.True(t, out.push("", websocket.TextMessage, []byte("a")))
+	assert.True(t, out.push("", websocket.TextMessage, []byte("b")))
+
+	assert.Eventually(t, func() bool {
+		written, _, _ := conn.snapshot()
+		return len(written) == 2
+	}, time.Second, 5*time.Millisecond)
+	written, deadlines, _ := conn.snapshot()
+	assert.Equal(t, []string{"a", "b"}, written)
+	assert.Equal(t, 2, deadlines)
+}
+
+func TestOutboxCoalescesQueuedUpdates(t *testing.T) {
+	conn := &fakeConn{block: make(chan struct{})}
+	out := newOutbox(conn)
+	defer out.stop()
+
+	out.push("", websocket.TextMessage, []byte("first"))
+	assert.Eventually(t, func() bool {
+		out.mu.Lock()
+		defer out.mu.Unlock()
+		return len(out.queue) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	out.push("MOVE:1", websocket.TextMessage, []byte("move1"))
+	out.push("", websocket.TextMessage, []byte("shoot"))
+	out.push("MOVE:1", websocket.TextMessage, []byte("move2"))
+	close(conn.block)
+
+	assert.Eventually(t, func() bool {
+		written, _, _ := conn.snapshot()
+		return len(written) == 3
+	}, time.Second, 5*time.Millisecond)
+	written, _, _ := conn.snapshot()
+	assert.Equal(t, []string{"first", "move2", "shoot"}, written)
+}
+
+func TestOutboxDropsStaleUpdatesWhenFull(t *testing.T) {
+	out := &outbox{conn: &fakeConn{}, wake: make(chan struct{}, 1), done: make(chan struct{})}
+
+	assert.True(t, out.push("MOVE:1", websocket.TextMessage, []byte("move")))
+	for i := 1; i < outboxSize; i++ {
+		assert.True(t, out.push("", websocket.TextMessage, []byte("msg")))
+	}
+	assert.True(t, out.push("", websocket.TextMessage, []byte("last")))
+	assert.Len(t, out.queue, outboxSize)
+	assert.Equal(t, "", out.queue[0].key)
+
+	assert.False(t, out.push("", websocket.TextMessage, []byte("overflow")))
+}
+
+func TestPlayerConnectionDisconnectsBackedUpClient(t *testing.T) {
+	conn := &fakeConn{}
+	out := &outbox{conn: conn, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	player := &PlayerConnection{ID: "slow", outbox: out}
+
+	for i := 0; i < outboxSize; i++ {
+		player.Send("", websocket.TextMessage, []byte("msg"))
+	}
+	_, _, closed := conn.snapshot()
+	assert.False(t, closed)
+
+	player.Send("", websocket.TextMessage, []byte("overflow"))
+	_, _, closed = conn.snapshot()
+	assert.True(t, closed)
+	assert.False(t, out.push("", websocket.TextMessage, []byte("after")))
+}
+
+func TestOutboxClosesConnectionOnWriteError(t *testing.T) {
+	conn := &fakeConn{err: errors.New("broken pipe")}
+	out := newOutbox(conn)
+
+	out.push("", websocket.TextMessage, []byte("a"))
+	assert.Eventually(t, func() bool {
+		_, _, closed := conn.snapshot()
+		return closed
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestOutboxFinishClosesAfterQueuedMessages(t *testing.T) {
+	conn := &fakeConn{}
+	out := newOutbox(conn)
+
+	out.push("", websocket.TextMessage, []byte("kicked"))
+	out.finish()
+	assert.True(t, out.push("", websocket.TextMessage, []byte("late")))
+
+	assert.Eventually(t, func() bool {
+		_, _, closed := conn.snapshot()
+		return closed
+	}, time.Second, 5*time.Millisecond)
+	written, _, _ := conn.snapshot()
+	assert.Equal(t, []string{"kicked"}, written)
+}
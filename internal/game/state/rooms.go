@@ -0,0 +1,66 @@
+package state
+
+import "sync"
+
+// RoomListener is notified when the first local player of a room connects to this instance
+// and when the last one is gone, so the instance only listens to the rooms it serves
+type RoomListener interface {
+	RoomActivated(roomId string)
+	RoomDeactivated(roomId string)
+}
+
+var (
+	roomListener RoomListener
+	roomPlayers  = make(map[string]int)
+	roomsMu      sync.Mutex
+)
+
+// SetRoomListener registers the listener of local room activity
+func SetRoomListener(listener RoomListener) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	roomListener = listener
+}
+
+// GetLocalRooms returns the rooms with players connected to this instance
+func GetLocalRooms() []string {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+
+	rooms := make([]string, 0, len(roomPlayers))
+	for roomId := range roomPlayers {
+		rooms = append(rooms, roomId)
+	}
+	return rooms
+}
+
+func addRoomPlayer(roomId string) {
+	roomsMu.Lock()
+	roomPlayers[roomId]++
+	activated := roomPlayers[roomId] == 1
+	listener := roomListener
+	roomsMu.Unlock()
+
+	if activated && listener != nil {
+		listener.RoomActivated(roomId)
+	}
+}
+
+func removeRoomPlayer(roomId string) {
+	roomsMu.Lock()
+	if roomPlayers[roomId] == 0 {
+		roomsMu.Unlock()
+		return
+	}
+	roomPlayers[roomId]--
+	deactivated := roomPlayers[roomId] == 0
+	if deactivated {
+		delete(roomPlayers, roomId)
+	}
+	listener := roomListener
+	roomsMu.Unlock()
+
+	if deactivated && listener != nil {
+		listener.RoomDeactivated(roomId)
+	}
+}
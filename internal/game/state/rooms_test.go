@@ -0,0 +1,40 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingListener struct {
+	activated   []string
+	deactivated []string
+}
+
+func (l *recordingListener) RoomActivated(roomId string) {
+	l.activated = append(l.activated, roomId)
+}
+
+func (l *recordingListener) RoomDeactivated(roomId string) {
+	l.deactivated = append(l.deactivated, roomId)
+}
+
+func TestRoomListenerFollowsLocalPlayers(t *testing.T) {
+	listener := &recordingListener{}
+	SetRoomListener(listener)
+	defer SetRoomListener(nil)
+
+	RegisterPlayer("rl1", "roomL", nil, "")
+	RegisterPlayer("rl2", "roomL", nil, "")
+	RegisterPlayer("rl1", "roomL", nil, "")
+	assert.Equal(t, []string{"roomL"}, listener.activated)
+	assert.Contains(t, GetLocalRooms(), "roomL")
+
+	UnregisterPlayer("rl1")
+	assert.Empty(t, listener.deactivated)
+
+	UnregisterPlayer("rl2")
+	UnregisterPlayer("rl2")
+	assert.Equal(t, []string{"roomL"}, listener.deactivated)
+	assert.NotContains(t, GetLocalRooms(), "roomL")
+}
@@ -2,6 +2,7 @@ package state
 
 import (
 	"log"
+	"math"
 	"sync"
 
 	"context"
@@ -23,6 +24,7 @@ type Bullet struct {
 	Position Position `json:"position"`
 	Speed    float64  `json:"speed"`
 	OwnerId  string   `json:"ownerId"`
+	Team1    bool     `json:"team1"`
 }
 
 type Fortress struct {
@@ -38,18 +40,85 @@ type PlayerState struct {
 	Position Position   `json:"position"`
 	Health   int        `json:"health"`
 	Team1    bool       `json:"team1"`
+	Latency  int64      `json:"latency"`
 	PlayerMu sync.Mutex `json:"-"`
 }
 
 type GameState struct {
 	Timestamp  int64                   `json:"timestamp"`
+	Tick       int64                   `json:"tick"`
 	Players    map[string]*PlayerState `json:"players"`
 	Bullets    map[string]*Bullet      `json:"bullets"`
 	Fortresses []*Fortress             `json:"fortress"`
+	Respawns   map[string]int64        `json:"respawns,omitempty"`
 	RoomId     string                  `json:"-"`
+	Fence      int64                   `json:"-"`
 	GameMu     sync.Mutex              `json:"-"`
 }
 
+// ScheduleRespawn registers the unix millis time at which a dead player must be revived.
+// The caller must hold GameMu.
+func (g *GameState) ScheduleRespawn(playerId string, at time.Time) {
+	if g.Respawns == nil {
+		g.Respawns = make(map[string]int64)
+	}
+	g.Respawns[playerId] = at.UnixMilli()
+}
+
+// maxLagCompensation caps how far back in time a shot can be moved
+const maxLagCompensation = 150 * time.Millisecond
+
+// AddBullet adds a shot to the game, moved forward by the time it took to reach the server,
+// which is half of the round trip time of its owner. The caller must hold GameMu.
+func (g *GameState) AddBullet(bullet *Bullet) {
+	if owner, ok := g.Players[bullet.OwnerId]; ok && owner.Latency > 0 {
+		lag := time.Duration(owner.Latency) * time.Millisecond / 2
+		if lag > maxLagCompensation {
+			lag = maxLagCompensation
+		}
+		bullet.Position.X += math.Cos(bullet.Position.Angle) * bullet.Speed * lag.Seconds()
+		bullet.Position.Y += math.Sin(bullet.Position.Angle) * bullet.Speed * lag.Seconds()
+	}
+	g.Bullets[bullet.ID] = bullet
+}
+
+// GameTimers tells a client, in millis, how long a game has been running and when its dead players respawn
+type GameTimers struct {
+	ServerTime int64            `json:"serverTime"`
+	Elapsed    int64            `json:"elapsed"`
+	Respawns   map[string]int64 `json:"respawns"`
+}
+
+// Timers returns the timers of the game at now. The caller must hold GameMu.
+func (g *GameState) Timers(now time.Time) GameTimers {
+	timers := GameTimers{
+		ServerTime: now.UnixMilli(),
+		Elapsed:    now.Sub(time.Unix(g.Timestamp, 0)).Milliseconds(),
+		Respawns:   make(map[string]int64, len(g.Respawns)),
+	}
+	for playerId, at := range g.Respawns {
+		remaining := at - now.UnixMilli()
+		if remaining < 0 {
+			remaining = 0
+		}
+		timers.Respawns[playerId] = remaining
+	}
+	return timers
+}
+
+// DueRespawns removes and returns the players whose respawn time has been reached.
+// The caller must hold GameMu.
+func (g *GameState) DueRespawns(now time.Time) []string {
+	due := []string{}
+	for playerId, at := range g.Respawns {
+		if at <= now.UnixMilli() {
+			due = append(due, playerId)
+			delete(g.Respawns, playerId)
+		}
+	}
+	return due
+}
+
 type PlayerConnection struct {
 	ID        string
 	RoomId    string
@@ -57,6 +126,37 @@ type PlayerConnection struct {
 	Connected bool
 	Conn      *websocket.Conn
 	ConnMu    sync.Mutex
+	outbox    *outbox
+	protocol  string
+}
+
+// Protocol returns the websocket subprotocol negotiated by the connection
+func (p *PlayerConnection) Protocol() string {
+	p.ConnMu.Lock()
+	defer p.ConnMu.Unlock()
+	return p.protocol
+}
+
+// connOutbox starts the writer of a connection, tests register players without one
+func connOutbox(conn *websocket.Conn) *outbox {
+	if conn == nil {
+		return nil
+	}
+	return newOutbox(conn)
+}
+
+// Send queues a message for the player without blocking the caller.
+// Queued messages with the same key are replaced, and a client that stays backed up is disconnected
+func (p *PlayerConnection) Send(key string, frameType int, data []byte) {
+	p.ConnMu.Lock()
+	out := p.outbox
+	p.ConnMu.Unlock()
+	if out == nil {
+		return
+	}
+	if !out.push(key, frameType, data) && out.close() {
+		log.Printf("Player %s is not reading its messages, closing its connection", p.ID)
+	}
 }
 
 var (
@@ -65,47 +165,100 @@ var (
 	ctx       = context.Background()
 )
 
+// ConnectionTTL is how long the mark of an open connection outlives the last pong of its client,
+// so the players of a crashed instance stop counting as connected
+const ConnectionTTL = 30 * time.Second
+
+// instance owns the connection marks set by this process
+var instance string
+
+// SetInstance sets the id of this instance, stored in the connection marks of its players
+func SetInstance(id string) {
+	instance = id
+}
+
+// releaseConnScript deletes the connection mark only if it belongs to the caller, the player may
+// already be connected to another instance
+var releaseConnScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// setConn marks the player as connected to this instance
 func setConn(id string) {
 	if db.Rdb == nil {
 		return
 	}
-	db.Rdb.Set(ctx, "ws:"+id, "connected", 0)
+	if err := db.Rdb.Set(ctx, db.ConnectionKey(id), instance, ConnectionTTL).Err(); err != nil {
+		log.Print("Error setting ws conn")
+	}
 }
 
-func RegisterPlayer(id string, roomId string, conn *websocket.Conn) {
+// RefreshConnection renews the mark of the open connection of a player, setting it again in
+// case it expired while the client was still answering
+func RefreshConnection(id string) {
+	setConn(id)
+}
+
+// RegisterPlayer tracks the connection of a player and the subprotocol it negotiated. It returns
+// true when the player was already known and the connection replaces the one it lost, resuming its session
+func RegisterPlayer(id string, roomId string, conn *websocket.Conn, protocol string) bool {
 	player := GetPlayer(id)
 	playersMu.Lock()
-	defer playersMu.Unlock()
 	if player == nil {
 		setConn(id)
 		players[id] = &PlayerConnection{
 			ID:        id,
 			Connected: true,
 			Conn:      conn,
+			outbox:    connOutbox(conn),
+			protocol:  protocol,
 			GameState: nil,
 			RoomId:    roomId,
 		}
-	} else {
-		player.ConnMu.Lock()
-		player.Conn = conn
-		player.Connected = true
-		player.ConnMu.Unlock()
+		playersMu.Unlock()
+		addRoomPlayer(roomId)
+		return false
 	}
+
+	setConn(id)
+	player.ConnMu.Lock()
+	if player.outbox != nil {
+		player.outbox.stop()
+	}
+	player.Conn = conn
+	player.outbox = connOutbox(conn)
+	player.protocol = protocol
+	player.Connected = true
+	player.ConnMu.Unlock()
+	playersMu.Unlock()
+	return true
 }
 
-func UnregisterPlayerDelayed(id string, delay time.Duration, LeaveRoom func(string) error) {
-	go func() {
-		playersMu.Lock()
-		player := players[id]
-		if player == nil {
-			playersMu.Unlock()
-			return
-		}
-		player.ConnMu.Lock()
-		player.Connected = false
+// UnregisterPlayerDelayed marks a player as disconnected when conn is still its connection and
+// removes it from its room if it doesn't reconnect within delay. It returns false when the
+// player is unknown or already uses a newer connection
+func UnregisterPlayerDelayed(id string, conn *websocket.Conn, delay time.Duration, LeaveRoom func(string) error) bool {
+	playersMu.Lock()
+	player := players[id]
+	if player == nil {
+		playersMu.Unlock()
+		return false
+	}
+	player.ConnMu.Lock()
+	if player.Conn != conn {
 		player.ConnMu.Unlock()
 		playersMu.Unlock()
-		deletePlayerConn(id)
+		return false
+	}
+	player.Connected = false
+	player.ConnMu.Unlock()
+	playersMu.Unlock()
+	deletePlayerConn(id)
+
+	go func() {
 		time.Sleep(delay)
 
 		playersMu.Lock()
@@ -113,6 +266,7 @@ func UnregisterPlayerDelayed(id string, delay time.Duration, LeaveRoom func(stri
 		if player != nil && !player.Connected {
 			delete(players, id)
 			playersMu.Unlock()
+			removeRoomPlayer(player.RoomId)
 			if getConn(id) {
 				LeaveRoom(id)
 			}
@@ -122,16 +276,24 @@ func UnregisterPlayerDelayed(id string, delay time.Duration, LeaveRoom func(stri
 			log.Printf("Player %s Reconnected on time", id)
 		}
 	}()
+	return true
 }
 
+// deletePlayerConn removes the connection mark of a player unless it was set by another instance
 func deletePlayerConn(id string) {
-	if err := db.Rdb.Del(ctx, "ws:"+id).Err(); err != nil {
+	if db.Rdb == nil {
+		return
+	}
+	if err := releaseConnScript.Run(ctx, db.Rdb, []string{db.ConnectionKey(id)}, instance).Err(); err != nil {
 		log.Print("Error deleting conn")
 	}
 }
 
 func getConn(id string) bool {
-	_, err := db.Rdb.Get(ctx, "ws:"+id).Result()
+	if db.Rdb == nil {
+		return true
+	}
+	_, err := db.Rdb.Get(ctx, db.ConnectionKey(id)).Result()
 	if err == redis.Nil {
 		return true
 	} else if err != nil {
@@ -142,16 +304,74 @@ func getConn(id string) bool {
 	return false
 }
 
+// IsPlayerConnected reports if a player has an open connection on this or any other instance
+func IsPlayerConnected(id string) bool {
+	if player := GetPlayer(id); player != nil {
+		player.ConnMu.Lock()
+		connected := player.Connected
+		player.ConnMu.Unlock()
+		if connected {
+			return true
+		}
+	}
+	if db.Rdb == nil {
+		return false
+	}
+	exists, err := db.Rdb.Exists(ctx, db.ConnectionKey(id)).Result()
+	if err != nil {
+		log.Print("Error retrieving ws conn")
+		return true
+	}
+	return exists > 0
+}
+
 func UnregisterPlayer(id string) {
 	player := GetPlayer(id)
 	if player == nil {
 		return
 	}
+	player.ConnMu.Lock()
+	if player.outbox != nil {
+		player.outbox.stop()
+	}
 	if player.Conn != nil {
 		player.Conn.Close()
 	}
+	player.ConnMu.Unlock()
 
+	playersMu.Lock()
+	if players[id] != player {
+		playersMu.Unlock()
+		return
+	}
 	delete(players, id)
+	playersMu.Unlock()
+	removeRoomPlayer(player.RoomId)
+}
+
+// ReleasePlayer forgets a player removed from its room by someone else, like a kick or the
+// deletion of the room. Its connection is closed after the messages already queued for it,
+// which tell the client why, are written
+func ReleasePlayer(id string, roomId string) {
+	playersMu.Lock()
+	player := players[id]
+	if player == nil || player.RoomId != roomId {
+		playersMu.Unlock()
+		return
+	}
+	delete(players, id)
+	playersMu.Unlock()
+
+	player.ConnMu.Lock()
+	player.Connected = false
+	if player.outbox != nil {
+		player.outbox.finish()
+	} else if player.Conn != nil {
+		player.Conn.Close()
+	}
+	player.ConnMu.Unlock()
+	deletePlayerConn(id)
+	removeRoomPlayer(roomId)
 }
 
 func GetPlayer(id string) *PlayerConnection {
@@ -0,0 +1,89 @@
+package state
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddBulletCompensatesOwnerLatency(t *testing.T) {
+	game := &GameState{
+		Players: map[string]*PlayerState{"p1": {ID: "p1", Latency: 100}},
+		Bullets: map[string]*Bullet{},
+	}
+
+	game.AddBullet(&Bullet{ID: "b1", OwnerId: "p1", Speed: 400})
+
+	assert.InDelta(t, 20, game.Bullets["b1"].Position.X, 0.001)
+	assert.InDelta(t, 0, game.Bullets["b1"].Position.Y, 0.001)
+}
+
+func TestAddBulletCapsLagCompensation(t *testing.T) {
+	game := &GameState{
+		Players: map[string]*PlayerState{"p1": {ID: "p1", Latency: 2000}},
+		Bullets: map[string]*Bullet{},
+	}
+
+	game.AddBullet(&Bullet{ID: "b1", OwnerId: "p1", Speed: 400, Position: Position{Angle: math.Pi / 2}})
+
+	assert.InDelta(t, 0, game.Bullets["b1"].Position.X, 0.001)
+	assert.InDelta(t, 60, game.Bullets["b1"].Position.Y, 0.001)
+}
+
+func TestAddBulletWithoutLatency(t *testing.T) {
+	game := &GameState{
+		Players: map[string]*PlayerState{"p1": {ID: "p1"}},
+		Bullets: map[string]*Bullet{},
+	}
+
+	game.AddBullet(&Bullet{ID: "b1", OwnerId: "p1", Speed: 400, Position: Position{X: 5, Y: 5}})
+
+	assert.Equal(t, Position{X: 5, Y: 5}, game.Bullets["b1"].Position)
+}
+
+func TestRegisterPlayerReportsResumedSessions(t *testing.T) {
+	defer UnregisterPlayer("resume1")
+
+	assert.False(t, RegisterPlayer("resume1", "roomR", nil, ""))
+	assert.True(t, UnregisterPlayerDelayed("resume1", nil, time.Hour, func(string) error { return nil }))
+	assert.False(t, IsPlayerConnected("resume1"))
+
+	assert.True(t, RegisterPlayer("resume1", "roomR", nil, ""))
+	assert.True(t, IsPlayerConnected("resume1"))
+}
+
+func TestRegisterPlayerKeepsNegotiatedProtocol(t *testing.T) {
+	defer UnregisterPlayer("proto1")
+
+	RegisterPlayer("proto1", "roomR", nil, "json")
+	assert.Equal(t, "json", GetPlayer("proto1").Protocol())
+
+	RegisterPlayer("proto1", "roomR", nil, "binary")
+	assert.Equal(t, "binary", GetPlayer("proto1").Protocol())
+}
+
+func TestUnregisterPlayerDelayedIgnoresReplacedConnections(t *testing.T) {
+	defer UnregisterPlayer("resume2")
+	RegisterPlayer("resume2", "roomR", nil, "")
+
+	assert.False(t, UnregisterPlayerDelayed("resume2", &websocket.Conn{}, time.Hour, func(string) error { return nil }))
+	assert.True(t, IsPlayerConnected("resume2"))
+	assert.False(t, UnregisterPlayerDelayed("unknown", nil, time.Hour, func(string) error { return nil }))
+}
+
+func TestGameTimers(t *testing.T) {
+	now := time.Unix(1000, 0)
+	game := &GameState{
+		Timestamp: 990,
+		Respawns:  map[string]int64{"p1": now.UnixMilli() + 2500, "p2": now.UnixMilli() - 10},
+	}
+
+	timers := game.Timers(now)
+
+	assert.Equal(t, now.UnixMilli(), timers.ServerTime)
+	assert.Equal(t, int64(10000), timers.Elapsed)
+	assert.Equal(t, map[string]int64{"p1": 2500, "p2": 0}, timers.Respawns)
+}
@@ -0,0 +1,113 @@
+package user
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryUserRepository keeps users and their stats in the process memory for single node runs
+type MemoryUserRepository struct {
+	users  map[uint]User
+	stats  map[uint]UserStats
+	nextID uint
+	mu     sync.RWMutex
+}
+
+func NewMemoryUserRepository() UserRepository {
+	return &MemoryUserRepository{
+		users: make(map[uint]User),
+		stats: make(map[uint]UserStats),
+	}
+}
+
+func (u *MemoryUserRepository) findByUsername(username string) (User, bool) {
+	for _, user := range u.users {
+		if user.Username == username {
+			return user, true
+		}
+	}
+	return User{}, false
+}
+
+func (u *MemoryUserRepository) CreateUser(username, password string) (*User, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), 14)
+	if err != nil {
+		return nil, apperrors.NewAppError(500, "Error hashing password", err)
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, exists := u.findByUsername(username); exists {
+		return nil, apperrors.NewAppError(409, "User already exists", errors.New("username already exists"))
+	}
+
+	u.nextID++
+	newUser := User{
+		ID:       u.nextID,
+		Username: username,
+		Password: string(hashed),
+	}
+	u.users[newUser.ID] = newUser
+	u.stats[newUser.ID] = UserStats{
+		ID:     newUser.ID,
+		UserID: newUser.ID,
+	}
+
+	return &newUser, nil
+}
+
+func (u *MemoryUserRepository) ValidateUser(username, password string) (*User, error) {
+	u.mu.RLock()
+	user, ok := u.findByUsername(username)
+	u.mu.RUnlock()
+	if !ok {
+		return nil, apperrors.NewAppError(404, ERROR_USER_NOT_FOUND, nil)
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if err != nil {
+		return nil, apperrors.NewAppError(400, "Invalid password", err)
+	}
+
+	return &user, nil
+}
+
+func (u *MemoryUserRepository) GetUserUsername(id int) (string, error) {
+	user, err := u.GetUser(id)
+	if err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+func (u *MemoryUserRepository) GetUser(id int) (*User, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	user, ok := u.users[uint(id)]
+	if !ok {
+		return nil, apperrors.NewAppError(404, ERROR_USER_NOT_FOUND, nil)
+	}
+	return &user, nil
+}
+
+func (u *MemoryUserRepository) FetchUserStats(userID int) (UserStats, error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	stats, ok := u.stats[uint(userID)]
+	if !ok {
+		return UserStats{}, apperrors.NewAppError(404, "User stats not found", nil)
+	}
+	return stats, nil
+}
+
+func (u *MemoryUserRepository) UpdateUserStats(stats *UserStats) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.stats[stats.UserID] = *stats
+	return nil
+}
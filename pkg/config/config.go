@@ -2,6 +2,7 @@ package config
 
 import (
 	"log"
+	"os"
 
 	"github.com/joho/godotenv"
 )
@@ -11,3 +12,9 @@ func LoadEnv() {
 		log.Println("No .env file found")
 	}
 }
+
+// UseMemoryStorage reports whether the server runs as a single node without Redis and
+// Postgres, selected with STORAGE=memory
+func UseMemoryStorage() bool {
+	return os.Getenv("STORAGE") == "memory"
+}
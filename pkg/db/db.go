@@ -31,10 +31,11 @@ func Init() {
 	if err != nil {
 		log.Fatalf("error connecting to database: %v", err)
 	}
-	redisDBConnection()
+	InitRedis()
 }
 
-func redisDBConnection() {
+// InitRedis connects only to Redis, for the tools that do not need Postgres
+func InitRedis() {
 	ctx := context.Background()
 	useTLS := os.Getenv("REDIS_TLS") == "true"
 
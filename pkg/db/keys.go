@@ -0,0 +1,71 @@
+package db
+
+// KeyPrefix namespaces every key written by the server, so it can share a Redis database
+const KeyPrefix = "ttb:"
+
+// RoomsKey is the sorted set of room ids scored by creation time
+const RoomsKey = KeyPrefix + "rooms"
+
+// PlayerRoomsKey is the set of players with a room mapping
+const PlayerRoomsKey = KeyPrefix + "player-rooms"
+
+// ControlEventsKey is the stream of the events every instance listens to, like game handoffs
+const ControlEventsKey = KeyPrefix + "control"
+
+// RoomKey holds the JSON document of a room
+func RoomKey(roomID string) string {
+	return KeyPrefix + "room:" + roomID
+}
+
+// RoomEventsKey is the stream of the events of a room
+func RoomEventsKey(roomID string) string {
+	return RoomKey(roomID) + ":events"
+}
+
+// LeaderKey holds the instance leading the game of a room and its fencing token
+func LeaderKey(roomID string) string {
+	return RoomKey(roomID) + ":leader"
+}
+
+// FenceKey is the fencing counter of the leaders of a room
+func FenceKey(roomID string) string {
+	return RoomKey(roomID) + ":fence"
+}
+
+// CheckpointKey holds the last checkpoint of the game of a room
+func CheckpointKey(roomID string) string {
+	return RoomKey(roomID) + ":checkpoint"
+}
+
+// RoomKeysKey is the set of the game keys of a room
+func RoomKeysKey(roomID string) string {
+	return RoomKey(roomID) + ":keys"
+}
+
+// ChatKey is the list of the last chat messages of a room
+func ChatKey(roomID string) string {
+	return RoomKey(roomID) + ":chat"
+}
+
+// PlayerRoomKey holds the id of the room a player is in
+func PlayerRoomKey(playerID string) string {
+	return KeyPrefix + "player-room:" + playerID
+}
+
+// ConnectionKey marks that a player has an open websocket on some instance
+func ConnectionKey(playerID string) string {
+	return KeyPrefix + "ws:" + playerID
+}
+
+// InstancesKey is the set of the ids of the registered server instances
+const InstancesKey = KeyPrefix + "instances"
+
+// InstanceKey holds the last heartbeat of a server instance
+func InstanceKey(instanceID string) string {
+	return KeyPrefix + "instance:" + instanceID
+}
+
+// LockKey holds the instance that owns a cluster wide task
+func LockKey(name string) string {
+	return KeyPrefix + "lock:" + name
+}
@@ -0,0 +1,21 @@
+package actions
+
+import (
+	"encoding/json"
+
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/internal/game"
+	"github.com/thesrcielos/TopTankBattle/websocket/message"
+)
+
+var ChatService *game.ChatService
+
+func HandleChat(playerId string, msg message.Message, gameService game.GameService) error {
+	var payload message.ChatPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return apperrors.NewAppError(400, "Invalid CHAT payload", err)
+	}
+
+	_, err := ChatService.SendMessage(playerId, payload.Channel, payload.Text)
+	return err
+}
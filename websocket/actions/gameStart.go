@@ -2,18 +2,17 @@ package actions
 
 import (
 	"encoding/json"
-	"log"
 
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game"
 	"github.com/thesrcielos/TopTankBattle/websocket/message"
 )
 
-func HandleGameStart(playerId string, msg message.Message, gameService game.GameService) {
+func HandleGameStart(playerId string, msg message.Message, gameService game.GameService) error {
 	var gameStartPayload message.GameStartPayload
 	if err := json.Unmarshal(msg.Payload, &gameStartPayload); err != nil {
-		log.Println("Error decoding: ", err)
-		return
+		return apperrors.NewAppError(400, "Invalid GAME_START payload", err)
 	}
 
-	gameService.StartGame(playerId, gameStartPayload.RoomId, false)
+	return gameService.StartGame(playerId, gameStartPayload.RoomId, false)
 }
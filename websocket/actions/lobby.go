@@ -0,0 +1,44 @@
+package actions
+
+import (
+	"encoding/json"
+
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/internal/game"
+	"github.com/thesrcielos/TopTankBattle/websocket/message"
+)
+
+var RoomService *game.RoomService
+
+func HandleKickPlayer(playerId string, msg message.Message, gameService game.GameService) error {
+	var payload message.RoomKickRequestPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return apperrors.NewAppError(400, "Invalid KICK_PLAYER payload", err)
+	}
+
+	return RoomService.KickPlayer(playerId, payload.RoomId, payload.PlayerId)
+}
+
+func HandleSwitchTeam(playerId string, msg message.Message, gameService game.GameService) error {
+	_, err := RoomService.SwitchTeam(playerId)
+	return err
+}
+
+func HandleReady(playerId string, msg message.Message, gameService game.GameService) error {
+	var payload message.ReadyPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return apperrors.NewAppError(400, "Invalid READY payload", err)
+	}
+
+	_, err := RoomService.SetReady(playerId, payload.Ready)
+	return err
+}
+
+func HandleDeleteRoom(playerId string, msg message.Message, gameService game.GameService) error {
+	var payload message.RoomDeletionRequestPayload
+	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+		return apperrors.NewAppError(400, "Invalid DELETE_ROOM payload", err)
+	}
+
+	return RoomService.DeleteRoom(playerId, payload.Room)
+}
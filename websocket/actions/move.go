@@ -2,18 +2,17 @@ package actions
 
 import (
 	"encoding/json"
-	"log"
 
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game"
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
 	"github.com/thesrcielos/TopTankBattle/websocket/message"
 )
 
-func HandleMove(playerId string, msg message.Message, gameService game.GameService) {
+func HandleMove(playerId string, msg message.Message, gameService game.GameService) error {
 	var movePayload message.GameMovePayload
 	if err := json.Unmarshal(msg.Payload, &movePayload); err != nil {
-		log.Println("Error decoding", err)
-		return
+		return apperrors.NewAppError(400, "Invalid MOVE payload", err)
 	}
 	position := state.Position{
 		X:     movePayload.X,
@@ -21,4 +20,5 @@ func HandleMove(playerId string, msg message.Message, gameService game.GameServi
 		Angle: movePayload.Angle,
 	}
 	gameService.MovePlayer(playerId, position)
+	return nil
 }
@@ -4,12 +4,13 @@ import (
 	"encoding/json"
 
 	"github.com/google/uuid"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game"
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
 	"github.com/thesrcielos/TopTankBattle/websocket/message"
 )
 
-func HandleShoot(playerId string, msg message.Message, game game.GameService) {
+func HandleShoot(playerId string, msg message.Message, game game.GameService) error {
 	var payload struct {
 		OwnerId string  `json:"ownerId"`
 		X       float64 `json:"x"`
@@ -18,7 +19,10 @@ func HandleShoot(playerId string, msg message.Message, game game.GameService) {
 	}
 
 	if err := json.Unmarshal(msg.Payload, &payload); err != nil {
-		return
+		return apperrors.NewAppError(400, "Invalid SHOOT payload", err)
+	}
+	if payload.OwnerId == "" {
+		payload.OwnerId = playerId
 	}
 
 	bullet := &state.Bullet{
@@ -32,5 +36,5 @@ func HandleShoot(playerId string, msg message.Message, game game.GameService) {
 		Speed: 500,
 	}
 	game.ShootBullet(bullet)
-
+	return nil
 }
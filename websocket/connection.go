@@ -1,7 +1,7 @@
 package websocket
 
 import (
-	"encoding/json"
+	"errors"
 	"log"
 	"time"
 
@@ -10,25 +10,58 @@ import (
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
 	"github.com/thesrcielos/TopTankBattle/websocket/message"
 	"github.com/thesrcielos/TopTankBattle/websocket/router"
+	"github.com/thesrcielos/TopTankBattle/websocket/transport"
 )
 
 var RoomService *game.RoomService
 
 func listenPlayerMessages(playerId string, conn *websocket.Conn) {
+	done := make(chan struct{})
 	defer func() {
-		state.UnregisterPlayerDelayed(playerId, 20*time.Second, RoomService.LeaveRoom)
+		close(done)
+		if state.UnregisterPlayerDelayed(playerId, conn, 20*time.Second, RoomService.LeaveRoom) {
+			GameService.NotifyPlayerConnection(playerId, false)
+		}
 		conn.Close()
 	}()
 
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(pongHandler(playerId, conn))
+	go keepAlive(playerId, conn, done)
+
+	limiter := newRateLimiter(rateLimits)
 	for {
-		_, data, err := conn.ReadMessage()
+		frameType, data, err := conn.ReadMessage()
 		if err != nil {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				metrics.Add("oversized", 1)
+			}
 			log.Println("Error reading message:", err)
 			break
 		}
 
-		var msg message.Message
-		if err := json.Unmarshal(data, &msg); err != nil {
+		// frames that cannot be decoded are charged to the shared limit, so garbage cannot flood the server
+		msg, err := message.Decode(frameType, data)
+		msgType, requestId := otherMessages, ""
+		if err == nil {
+			msgType, requestId = msg.Type, msg.RequestId
+		}
+
+		switch limiter.check(msgType, time.Now()) {
+		case dropMessage:
+			continue
+		case warnClient:
+			transport.SendToPlayer(playerId, transport.OutgoingMessage{
+				Type:    "ERROR",
+				Payload: message.ErrorPayload{Code: 429, Message: "Too many messages, slow down", RequestId: requestId},
+			})
+			continue
+		case disconnectClient:
+			log.Printf("Disconnecting player %s for flooding", playerId)
+			return
+		}
+
+		if err != nil {
 			log.Println("Error decoding message:", err)
 			continue
 		}
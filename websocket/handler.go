@@ -1,7 +1,6 @@
 package websocket
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -12,10 +11,10 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
-	"github.com/redis/go-redis/v9"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game"
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
-	"github.com/thesrcielos/TopTankBattle/pkg/db"
+	"github.com/thesrcielos/TopTankBattle/websocket/message"
 )
 
 var (
@@ -23,11 +22,16 @@ var (
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
 		CheckOrigin:     func(r *http.Request) bool { return true },
+		Subprotocols:    message.Protocols,
 	}
 )
 
 var GameService game.GameService
 
+var ChatService *game.ChatService
+
+var LeaderLocator *game.LeaderLocator
+
 func WebSocketHandler(c echo.Context) error {
 	tokenString := c.QueryParam("token")
 
@@ -41,23 +45,34 @@ func WebSocketHandler(c echo.Context) error {
 		log.Println("WebSocket upgrade failed:", err)
 		return err
 	}
+	ws.SetReadLimit(maxMessageSize)
 
-	ctx := context.Background()
-	val, err := db.Rdb.Get(ctx, userID).Result()
-	fmt.Println("User room ID:", val)
-	if err == redis.Nil {
-		ws.WriteMessage(websocket.TextMessage, []byte("User room not found"))
+	hello, err := handshake(ws)
+	if err != nil {
+		log.Printf("Handshake failed for user %s: %v", userID, err)
 		ws.Close()
+		return nil
+	}
+
+	val, err := RoomService.GetPlayerRoom(userID)
+	fmt.Println("User room ID:", val)
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) && appErr.Code == http.StatusNotFound {
+		rejectClient(ws, http.StatusNotFound, "User room not found")
 		log.Printf("User room not found for user %s", userID)
 		return nil
 	} else if err != nil {
-		ws.WriteMessage(websocket.TextMessage, []byte("Error retrieving user room"))
-		ws.Close()
+		rejectClient(ws, http.StatusInternalServerError, "Error retrieving user room")
 		log.Printf("Error retrieving user room for user %s", userID)
 		return nil
 	}
 	log.Printf("Player connected: %s", userID)
-	state.RegisterPlayer(userID, val, ws)
+	resumed := state.RegisterPlayer(userID, val, ws, ws.Subprotocol())
+	welcome(userID, val, ws, hello, resumed)
+	if resumed {
+		GameService.NotifyPlayerConnection(userID, true)
+	}
+	LeaderLocator.Redirect(val, []string{userID})
 	go listenPlayerMessages(userID, ws)
 
 	return nil
@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/thesrcielos/TopTankBattle/internal/game"
+	"github.com/thesrcielos/TopTankBattle/internal/game/maps"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+	"github.com/thesrcielos/TopTankBattle/websocket/message"
+	"github.com/thesrcielos/TopTankBattle/websocket/transport"
+)
+
+// ServerVersion is the version of the server, it can be set at build time with -ldflags
+var ServerVersion = "dev"
+
+// handshakeTimeout is how long a client has to send its HELLO after connecting
+const handshakeTimeout = 10 * time.Second
+
+// capabilities are the optional features the server supports
+var capabilities = []string{"binary", "latency", "redirect", "chat"}
+
+var errHandshake = errors.New("handshake failed")
+
+type WelcomePayload struct {
+	ServerVersion   string             `json:"serverVersion"`
+	ProtocolVersion int                `json:"protocolVersion"`
+	Encoding        string             `json:"encoding"`
+	Capabilities    []string           `json:"capabilities"`
+	TickRate        int                `json:"tickRate"`
+	MapId           string             `json:"mapId"`
+	PlayerId        string             `json:"playerId"`
+	Resumed         bool               `json:"resumed"`
+	Room            *game.Room         `json:"room"`
+	Game            *state.GameState   `json:"game,omitempty"`
+	Timers          *state.GameTimers  `json:"timers,omitempty"`
+	Chat            []game.ChatMessage `json:"chat"`
+}
+
+// handshake waits for the HELLO of the client and rejects the ones speaking an unsupported version
+func handshake(ws *websocket.Conn) (*message.HelloPayload, error) {
+	ws.SetReadDeadline(time.Now().Add(handshakeTimeout))
+	defer ws.SetReadDeadline(time.Time{})
+
+	frameType, data, err := ws.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := message.Decode(frameType, data)
+	if err != nil || msg.Type != "HELLO" {
+		rejectClient(ws, http.StatusBadRequest, "The first message must be a HELLO")
+		return nil, errHandshake
+	}
+
+	var hello message.HelloPayload
+	if err := json.Unmarshal(msg.Payload, &hello); err != nil {
+		rejectClient(ws, http.StatusBadRequest, "The HELLO payload is not valid")
+		return nil, errHandshake
+	}
+	if hello.Version < message.MinProtocolVersion || hello.Version > message.ProtocolVersion {
+		rejectClient(ws, http.StatusUpgradeRequired, fmt.Sprintf("Protocol version %d is not supported, the server speaks versions %d to %d",
+			hello.Version, message.MinProtocolVersion, message.ProtocolVersion))
+		return nil, errHandshake
+	}
+	return &hello, nil
+}
+
+// rejectClient sends an ERROR frame to a client that is not registered yet and closes its connection
+func rejectClient(ws *websocket.Conn, code int, reason string) {
+	frameType, data, err := transport.Encode(ws.Subprotocol(), transport.OutgoingMessage{
+		Type:    "ERROR",
+		Payload: message.ErrorPayload{Code: code, Message: reason},
+	})
+	deadline := time.Now().Add(pingWait)
+	if err == nil {
+		ws.SetWriteDeadline(deadline)
+		ws.WriteMessage(frameType, data)
+	}
+	ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, http.StatusText(code)), deadline)
+	ws.Close()
+}
+
+// welcome tells a registered client how to talk to the server and where it is. A resumed
+// session gets the same full state and the recent chat, so it can catch up with what it missed
+// while disconnected
+func welcome(playerId string, roomId string, ws *websocket.Conn, hello *message.HelloPayload, resumed bool) {
+	room, snapshot, err := GameService.Snapshot(roomId)
+	if err != nil {
+		log.Println("Error getting room snapshot:", err)
+	}
+	var timers *state.GameTimers
+	if snapshot != nil {
+		t := snapshot.Timers(time.Now())
+		timers = &t
+	}
+	chat, err := ChatService.History(roomId, playerId)
+	if err != nil {
+		log.Println("Error getting chat history:", err)
+	}
+
+	encoding := ws.Subprotocol()
+	if encoding == "" {
+		encoding = message.JSONProtocol
+	}
+	transport.SendToPlayer(playerId, transport.OutgoingMessage{
+		Type: "WELCOME",
+		Payload: WelcomePayload{
+			ServerVersion:   ServerVersion,
+			ProtocolVersion: message.ProtocolVersion,
+			Encoding:        encoding,
+			Capabilities:    supportedCapabilities(hello.Capabilities),
+			TickRate:        game.TickRate,
+			MapId:           maps.ID,
+			PlayerId:        playerId,
+			Resumed:         resumed,
+			Room:            room,
+			Game:            snapshot,
+			Timers:          timers,
+			Chat:            chat,
+		},
+	})
+}
+
+// supportedCapabilities returns the capabilities asked by the client that the server supports
+func supportedCapabilities(requested []string) []string {
+	supported := []string{}
+	for _, capability := range requested {
+		for _, known := range capabilities {
+			if capability == known {
+				supported = append(supported, capability)
+				break
+			}
+		}
+	}
+	return supported
+}
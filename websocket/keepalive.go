@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+)
+
+// pongWait is how long a client can stay silent before its connection is considered dead
+const pongWait = state.ConnectionTTL
+
+// pingPeriod is how often the clients are pinged, it must be lower than pongWait
+const pingPeriod = 10 * time.Second
+
+// pingWait is the time allowed to write a ping
+const pingWait = 5 * time.Second
+
+// keepAlive pings the client until done is closed. Each ping carries its send time so the
+// pong tells the round trip time of the connection
+func keepAlive(playerId string, conn *websocket.Conn, done <-chan struct{}) {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		sent := strconv.FormatInt(time.Now().UnixNano(), 10)
+		if err := conn.WriteControl(websocket.PingMessage, []byte(sent), time.Now().Add(pingWait)); err != nil {
+			log.Printf("Error pinging player %s: %v", playerId, err)
+			return
+		}
+	}
+}
+
+// pongHandler extends the read deadline of the connection, keeps its shared connection mark
+// alive and reports its latency
+func pongHandler(playerId string, conn *websocket.Conn) func(string) error {
+	return func(appData string) error {
+		conn.SetReadDeadline(time.Now().Add(pongWait))
+		state.RefreshConnection(playerId)
+
+		sent, err := strconv.ParseInt(appData, 10, 64)
+		if err != nil {
+			return nil
+		}
+		GameService.ReportLatency(playerId, time.Since(time.Unix(0, sent)))
+		return nil
+	}
+}
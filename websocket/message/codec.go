@@ -0,0 +1,180 @@
+package message
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/gorilla/websocket"
+)
+
+// JSONProtocol and BinaryProtocol are the websocket subprotocols of the game.
+// Clients that don't ask for one of them talk JSON.
+const (
+	JSONProtocol   = "ttb.json"
+	BinaryProtocol = "ttb.binary.v1"
+)
+
+// Protocols lists the subprotocols in the order the server prefers them
+var Protocols = []string{BinaryProtocol, JSONProtocol}
+
+// Opcodes are the first byte of a binary frame. Messages without a compact layout are sent
+// as opJSON followed by the JSON of the message.
+const (
+	opJSON  byte = 0
+	opMove  byte = 1
+	opShoot byte = 2
+)
+
+var ErrInvalidFrame = errors.New("invalid binary frame")
+
+// Decode reads a message sent by a client in a text or binary frame.
+//
+// The client binary layouts, little endian, are:
+//
+//	MOVE:  opMove  x:float32 y:float32 angle:float32
+//	SHOOT: opShoot x:float32 y:float32 angle:float32
+func Decode(frameType int, data []byte) (Message, error) {
+	var msg Message
+	if frameType != websocket.BinaryMessage {
+		err := json.Unmarshal(data, &msg)
+		return msg, err
+	}
+	if len(data) == 0 {
+		return msg, ErrInvalidFrame
+	}
+
+	switch data[0] {
+	case opJSON:
+		err := json.Unmarshal(data[1:], &msg)
+		return msg, err
+	case opMove, opShoot:
+		r := reader{data: data[1:]}
+		x, y, angle := r.float32(), r.float32(), r.float32()
+		if r.failed {
+			return msg, ErrInvalidFrame
+		}
+		msg.Type = "MOVE"
+		if data[0] == opShoot {
+			msg.Type = "SHOOT"
+		}
+		msg.Payload = positionJSON(x, y, angle)
+		return msg, nil
+	}
+	return msg, ErrInvalidFrame
+}
+
+// EncodeBinary writes a message for a client that negotiated BinaryProtocol.
+//
+// The server binary layouts, little endian and with strings prefixed by their uint8 length, are:
+//
+//	MOVE:  opMove  playerId:string x:float32 y:float32 angle:float32
+//	SHOOT: opShoot id:string ownerId:string team1:uint8 x:float32 y:float32 angle:float32
+func EncodeBinary(msgType string, payload interface{}) ([]byte, error) {
+	if fields, ok := payload.(map[string]interface{}); ok {
+		if data, ok := encodeCompact(msgType, fields); ok {
+			return data, nil
+		}
+	}
+
+	data, err := json.Marshal(struct {
+		Type    string      `json:"type"`
+		Payload interface{} `json:"payload"`
+	}{Type: msgType, Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{opJSON}, data...), nil
+}
+
+// encodeCompact writes the fixed layout of a message, it fails for messages without one
+// or with fields that don't fit it
+func encodeCompact(msgType string, fields map[string]interface{}) ([]byte, bool) {
+	w := writer{}
+	switch msgType {
+	case "MOVE":
+		w.byte(opMove)
+		w.string(fields["playerId"])
+	case "SHOOT":
+		w.byte(opShoot)
+		w.string(fields["id"])
+		w.string(fields["ownerId"])
+		team1, ok := fields["team1"].(bool)
+		if !ok {
+			return nil, false
+		}
+		if team1 {
+			w.byte(1)
+		} else {
+			w.byte(0)
+		}
+	default:
+		return nil, false
+	}
+
+	position, ok := fields["position"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	w.float32(position["x"])
+	w.float32(position["y"])
+	w.float32(position["angle"])
+	return w.data, !w.failed
+}
+
+type writer struct {
+	data   []byte
+	failed bool
+}
+
+func (w *writer) byte(b byte) {
+	w.data = append(w.data, b)
+}
+
+func (w *writer) string(value interface{}) {
+	s, ok := value.(string)
+	if !ok || len(s) > math.MaxUint8 {
+		w.failed = true
+		return
+	}
+	w.data = append(w.data, byte(len(s)))
+	w.data = append(w.data, s...)
+}
+
+func (w *writer) float32(value interface{}) {
+	f, ok := value.(float64)
+	if !ok {
+		w.failed = true
+		return
+	}
+	w.data = binary.LittleEndian.AppendUint32(w.data, math.Float32bits(float32(f)))
+}
+
+type reader struct {
+	data   []byte
+	failed bool
+}
+
+func (r *reader) float32() float32 {
+	if len(r.data) < 4 {
+		r.failed = true
+		return 0
+	}
+	bits := binary.LittleEndian.Uint32(r.data)
+	r.data = r.data[4:]
+	return math.Float32frombits(bits)
+}
+
+// positionJSON writes the payload the handlers expect for a decoded position, without going through reflection
+func positionJSON(x, y, angle float32) json.RawMessage {
+	data := make([]byte, 0, 64)
+	data = append(data, `{"x":`...)
+	data = strconv.AppendFloat(data, float64(x), 'g', -1, 32)
+	data = append(data, `,"y":`...)
+	data = strconv.AppendFloat(data, float64(y), 'g', -1, 32)
+	data = append(data, `,"angle":`...)
+	data = strconv.AppendFloat(data, float64(angle), 'g', -1, 32)
+	return append(data, '}')
+}
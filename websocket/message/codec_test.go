@@ -0,0 +1,144 @@
+package message
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func movePayload() map[string]interface{} {
+	return map[string]interface{}{
+		"playerId": "42",
+		"position": map[string]interface{}{"x": 120.5, "y": 300.25, "angle": 1.5},
+	}
+}
+
+func shootPayload() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       "0b6a3b5e-8c1f-4f0e-9d59-2f0c6a1e7b11",
+		"ownerId":  "42",
+		"team1":    true,
+		"position": map[string]interface{}{"x": 120.5, "y": 300.25, "angle": 1.5},
+	}
+}
+
+func clientFrame(op byte, x, y, angle float32) []byte {
+	data := []byte{op}
+	for _, f := range []float32{x, y, angle} {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(f))
+	}
+	return data
+}
+
+func TestDecodeJSONFrame(t *testing.T) {
+	msg, err := Decode(websocket.TextMessage, []byte(`{"type":"MOVE","payload":{"x":1,"y":2,"angle":3}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "MOVE", msg.Type)
+	assert.JSONEq(t, `{"x":1,"y":2,"angle":3}`, string(msg.Payload))
+}
+
+func TestDecodeBinaryMoveAndShoot(t *testing.T) {
+	msg, err := Decode(websocket.BinaryMessage, clientFrame(opMove, 10, 20.5, 0.5))
+	assert.NoError(t, err)
+	assert.Equal(t, "MOVE", msg.Type)
+	var move GameMovePayload
+	assert.NoError(t, json.Unmarshal(msg.Payload, &move))
+	assert.Equal(t, GameMovePayload{X: 10, Y: 20.5, Angle: 0.5}, move)
+
+	msg, err = Decode(websocket.BinaryMessage, clientFrame(opShoot, 1, 2, 3))
+	assert.NoError(t, err)
+	assert.Equal(t, "SHOOT", msg.Type)
+}
+
+func TestDecodeBinaryJSONFallback(t *testing.T) {
+	data := append([]byte{opJSON}, `{"type":"GAME_START","payload":{"roomId":"r1"}}`...)
+	msg, err := Decode(websocket.BinaryMessage, data)
+	assert.NoError(t, err)
+	assert.Equal(t, "GAME_START", msg.Type)
+}
+
+func TestDecodeRejectsTruncatedFrames(t *testing.T) {
+	_, err := Decode(websocket.BinaryMessage, []byte{opMove, 1, 2})
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+	_, err = Decode(websocket.BinaryMessage, []byte{})
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+	_, err = Decode(websocket.BinaryMessage, []byte{99})
+	assert.ErrorIs(t, err, ErrInvalidFrame)
+}
+
+func TestEncodeBinaryMove(t *testing.T) {
+	data, err := EncodeBinary("MOVE", movePayload())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{opMove, 2, '4', '2'}, data[:4])
+	assert.Len(t, data, 4+12)
+	assert.Equal(t, float32(300.25), math.Float32frombits(binary.LittleEndian.Uint32(data[8:])))
+}
+
+func TestEncodeBinaryShoot(t *testing.T) {
+	data, err := EncodeBinary("SHOOT", shootPayload())
+	assert.NoError(t, err)
+	assert.Equal(t, opShoot, data[0])
+	assert.Len(t, data, 1+1+36+1+2+1+12)
+}
+
+func TestEncodeBinaryFallsBackToJSON(t *testing.T) {
+	data, err := EncodeBinary("ROOM_INFO", map[string]interface{}{"id": "r1"})
+	assert.NoError(t, err)
+	assert.Equal(t, opJSON, data[0])
+	assert.JSONEq(t, `{"type":"ROOM_INFO","payload":{"id":"r1"}}`, string(data[1:]))
+
+	malformed := movePayload()
+	malformed["position"] = "nowhere"
+	data, err = EncodeBinary("MOVE", malformed)
+	assert.NoError(t, err)
+	assert.Equal(t, opJSON, data[0])
+}
+
+func benchmarkEncode(b *testing.B, msgType string, payload map[string]interface{}, binaryProtocol bool) {
+	var size int
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var data []byte
+		var err error
+		if binaryProtocol {
+			data, err = EncodeBinary(msgType, payload)
+		} else {
+			data, err = json.Marshal(struct {
+				Type    string      `json:"type"`
+				Payload interface{} `json:"payload"`
+			}{msgType, payload})
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(data)
+	}
+	b.ReportMetric(float64(size), "bytes/msg")
+}
+
+func BenchmarkEncodeMoveJSON(b *testing.B)    { benchmarkEncode(b, "MOVE", movePayload(), false) }
+func BenchmarkEncodeMoveBinary(b *testing.B)  { benchmarkEncode(b, "MOVE", movePayload(), true) }
+func BenchmarkEncodeShootJSON(b *testing.B)   { benchmarkEncode(b, "SHOOT", shootPayload(), false) }
+func BenchmarkEncodeShootBinary(b *testing.B) { benchmarkEncode(b, "SHOOT", shootPayload(), true) }
+
+func benchmarkDecode(b *testing.B, frameType int, data []byte) {
+	b.ReportAllocs()
+	b.ReportMetric(float64(len(data)), "bytes/msg")
+	for i := 0; i < b.N; i++ {
+		if _, err := Decode(frameType, data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeMoveJSON(b *testing.B) {
+	benchmarkDecode(b, websocket.TextMessage, []byte(`{"type":"MOVE","payload":{"x":120.5,"y":300.25,"angle":1.5}}`))
+}
+
+func BenchmarkDecodeMoveBinary(b *testing.B) {
+	benchmarkDecode(b, websocket.BinaryMessage, clientFrame(opMove, 120.5, 300.25, 1.5))
+}
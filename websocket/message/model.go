@@ -4,9 +4,36 @@ import (
 	"encoding/json"
 )
 
+// ProtocolVersion is the version of the game protocol spoken by the server, clients down to
+// MinProtocolVersion are still accepted
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+)
+
+type HelloPayload struct {
+	Version      int      `json:"version"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// ErrorPayload mirrors apperrors.AppError, RequestId is the id of the message that failed
+type ErrorPayload struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	RequestId string `json:"requestId,omitempty"`
+}
+
+// AckPayload confirms that the command with RequestId succeeded
+type AckPayload struct {
+	RequestId string `json:"requestId"`
+	Type      string `json:"type"`
+}
+
+// Message is a client message, RequestId is an optional id echoed in the ACK or ERROR reply
 type Message struct {
-	Type    string          `json:"type"`
-	Payload json.RawMessage `json:"payload"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	RequestId string          `json:"requestId,omitempty"`
 }
 
 type MovePayload struct {
@@ -37,6 +64,16 @@ type RoomKickRequestPayload struct {
 	RoomId   string `json:"roomId"`
 }
 
+type ReadyPayload struct {
+	Ready bool `json:"ready"`
+}
+
+// ChatPayload is a chat message of a player, Channel is "all" or "team"
+type ChatPayload struct {
+	Channel string `json:"channel"`
+	Text    string `json:"text"`
+}
+
 type GameStartPayload struct {
 	RoomId string `json:"roomId"`
 }
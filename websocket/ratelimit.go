@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"expvar"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// limit is the sustained messages per second and the burst allowed for a message type
+type limit struct {
+	rate  float64
+	burst float64
+}
+
+// otherMessages is the key of the limit shared by the types without their own
+const otherMessages = "*"
+
+var defaultLimits = map[string]limit{
+	"MOVE":        {rate: 60, burst: 60},
+	"SHOOT":       {rate: 10, burst: 10},
+	"CHAT":        {rate: 1, burst: 5},
+	otherMessages: {rate: 5, burst: 10},
+}
+
+// rateLimits are the limits in use, they can be overridden with RATE_LIMITS=MOVE=60:60,SHOOT=10:10,*=5:10
+var rateLimits = loadRateLimits(os.Getenv("RATE_LIMITS"))
+
+// maxMessageSize is the largest frame a client can send, set with MAX_MESSAGE_SIZE
+var maxMessageSize = loadMaxMessageSize(os.Getenv("MAX_MESSAGE_SIZE"))
+
+// A client that keeps exceeding its limits first gets its messages dropped, then a warning
+// and finally is disconnected. Violations are forgotten after violationWindow.
+const (
+	violationWindow = 10 * time.Second
+	warnAfter       = 20
+	disconnectAfter = 100
+)
+
+// metrics counts the messages rejected by the limits, served on the admin metrics endpoint
+var metrics = expvar.NewMap("websocket")
+
+type action int
+
+const (
+	allowMessage action = iota
+	dropMessage
+	warnClient
+	disconnectClient
+)
+
+type tokenBucket struct {
+	limit  limit
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) take(now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.limit.rate
+	if b.tokens > b.limit.burst {
+		b.tokens = b.limit.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// rateLimiter keeps the buckets of a connection, it is only used by its reading goroutine
+type rateLimiter struct {
+	limits      map[string]limit
+	buckets     map[string]*tokenBucket
+	violations  int
+	windowStart time.Time
+	warned      bool
+}
+
+func newRateLimiter(limits map[string]limit) *rateLimiter {
+	return &rateLimiter{
+		limits:  limits,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// check spends a token of the message type and decides what to do with the message
+func (l *rateLimiter) check(msgType string, now time.Time) action {
+	key := msgType
+	if _, ok := l.limits[key]; !ok {
+		key = otherMessages
+	}
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{limit: l.limits[key], tokens: l.limits[key].burst, last: now}
+		l.buckets[key] = bucket
+	}
+	if bucket.take(now) {
+		return allowMessage
+	}
+
+	if now.Sub(l.windowStart) > violationWindow {
+		l.windowStart = now
+		l.violations = 0
+		l.warned = false
+	}
+	l.violations++
+	metrics.Add("dropped."+key, 1)
+
+	if l.violations >= disconnectAfter {
+		metrics.Add("disconnected", 1)
+		return disconnectClient
+	}
+	if l.violations >= warnAfter && !l.warned {
+		l.warned = true
+		metrics.Add("warned", 1)
+		return warnClient
+	}
+	return dropMessage
+}
+
+// loadRateLimits applies the overrides of a RATE_LIMITS value to the default limits
+func loadRateLimits(value string) map[string]limit {
+	limits := make(map[string]limit, len(defaultLimits))
+	for msgType, l := range defaultLimits {
+		limits[msgType] = l
+	}
+	if value == "" {
+		return limits
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		msgType, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		rate, burst, okSpec := strings.Cut(spec, ":")
+		r, errRate := strconv.ParseFloat(rate, 64)
+		b, errBurst := strconv.ParseFloat(burst, 64)
+		if !ok || !okSpec || errRate != nil || errBurst != nil || r <= 0 || b < 1 {
+			log.Printf("Ignoring invalid rate limit %q", entry)
+			continue
+		}
+		limits[msgType] = limit{rate: r, burst: b}
+	}
+	return limits
+}
+
+func loadMaxMessageSize(value string) int64 {
+	if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > 0 {
+		return size
+	}
+	return 4096
+}
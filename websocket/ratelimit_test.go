@@ -0,0 +1,55 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterAllowsBurstThenRefills(t *testing.T) {
+	limiter := newRateLimiter(map[string]limit{"SHOOT": {rate: 2, burst: 2}, otherMessages: {rate: 1, burst: 1}})
+	now := time.Now()
+
+	assert.Equal(t, allowMessage, limiter.check("SHOOT", now))
+	assert.Equal(t, allowMessage, limiter.check("SHOOT", now))
+	assert.Equal(t, dropMessage, limiter.check("SHOOT", now))
+
+	assert.Equal(t, allowMessage, limiter.check("SHOOT", now.Add(500*time.Millisecond)))
+	assert.Equal(t, allowMessage, limiter.check("GAME_START", now))
+}
+
+func TestRateLimiterEscalates(t *testing.T) {
+	limiter := newRateLimiter(map[string]limit{otherMessages: {rate: 1, burst: 1}})
+	now := time.Now()
+	limiter.check("MOVE", now)
+
+	actions := map[action]int{}
+	for i := 0; i < disconnectAfter; i++ {
+		actions[limiter.check("MOVE", now)]++
+	}
+
+	assert.Equal(t, disconnectAfter-2, actions[dropMessage])
+	assert.Equal(t, 1, actions[warnClient])
+	assert.Equal(t, 1, actions[disconnectClient])
+}
+
+func TestRateLimiterForgetsOldViolations(t *testing.T) {
+	limiter := newRateLimiter(map[string]limit{otherMessages: {rate: 0.01, burst: 1}})
+	now := time.Now()
+	limiter.check("MOVE", now)
+	for i := 0; i < warnAfter-1; i++ {
+		assert.Equal(t, dropMessage, limiter.check("MOVE", now))
+	}
+
+	assert.Equal(t, dropMessage, limiter.check("MOVE", now.Add(violationWindow+time.Second)))
+}
+
+func TestLoadRateLimits(t *testing.T) {
+	limits := loadRateLimits("MOVE=30:40, CHAT=bad, *=2:3")
+
+	assert.Equal(t, limit{rate: 30, burst: 40}, limits["MOVE"])
+	assert.Equal(t, defaultLimits["CHAT"], limits["CHAT"])
+	assert.Equal(t, limit{rate: 2, burst: 3}, limits[otherMessages])
+	assert.Equal(t, defaultLimits["SHOOT"], limits["SHOOT"])
+}
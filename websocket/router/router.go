@@ -1,23 +1,78 @@
 package router
 
 import (
+	"errors"
 	"log"
 
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
 	"github.com/thesrcielos/TopTankBattle/internal/game"
 	"github.com/thesrcielos/TopTankBattle/websocket/actions"
 	"github.com/thesrcielos/TopTankBattle/websocket/message"
+	"github.com/thesrcielos/TopTankBattle/websocket/transport"
 )
 
-var handlers = map[string]func(playerId string, payload message.Message, game game.GameService){
-	"MOVE":       actions.HandleMove,
-	"SHOOT":      actions.HandleShoot,
-	"GAME_START": actions.HandleGameStart,
+type Handler func(playerId string, payload message.Message, game game.GameService) error
+
+var handlers = map[string]Handler{
+	"MOVE":        actions.HandleMove,
+	"SHOOT":       actions.HandleShoot,
+	"GAME_START":  actions.HandleGameStart,
+	"KICK_PLAYER": actions.HandleKickPlayer,
+	"SWITCH_TEAM": actions.HandleSwitchTeam,
+	"READY":       actions.HandleReady,
+	"DELETE_ROOM": actions.HandleDeleteRoom,
+	"CHAT":        actions.HandleChat,
+}
+
+// commands are the messages answered with an ACK when they carry a request id,
+// high frequency game input is never acknowledged
+var commands = map[string]bool{
+	"GAME_START":  true,
+	"KICK_PLAYER": true,
+	"SWITCH_TEAM": true,
+	"READY":       true,
+	"DELETE_ROOM": true,
+	"CHAT":        true,
 }
 
+// sendToPlayer delivers the replies of the router
+var sendToPlayer = transport.SendToPlayer
+
+// RouteMessage runs the handler of a message. The errors are sent back to the player as ERROR
+// messages and the commands that succeed are acknowledged, both echoing the request id
 func RouteMessage(playerId string, msg message.Message, GameService game.GameService) {
-	if handler, ok := handlers[msg.Type]; ok {
-		handler(playerId, msg, GameService)
-	} else {
+	handler, ok := handlers[msg.Type]
+	if !ok {
 		log.Println("Tipo de mensaje desconocido:", msg.Type)
+		sendToPlayer(playerId, errorFrame(msg.RequestId, apperrors.NewAppError(400, "Unknown message type "+msg.Type, nil)))
+		return
+	}
+
+	if err := handler(playerId, msg, GameService); err != nil {
+		log.Printf("Error handling %s from player %s: %v", msg.Type, playerId, err)
+		sendToPlayer(playerId, errorFrame(msg.RequestId, err))
+		return
+	}
+	if commands[msg.Type] && msg.RequestId != "" {
+		sendToPlayer(playerId, transport.OutgoingMessage{
+			Type:    "ACK",
+			Payload: message.AckPayload{RequestId: msg.RequestId, Type: msg.Type},
+		})
+	}
+}
+
+// errorFrame builds the ERROR message of a failed request, errors that are not an AppError
+// are reported as internal errors without their details
+func errorFrame(requestId string, err error) transport.OutgoingMessage {
+	payload := message.ErrorPayload{
+		Code:      500,
+		Message:   "Internal server error",
+		RequestId: requestId,
+	}
+	var appErr *apperrors.AppError
+	if errors.As(err, &appErr) {
+		payload.Code = appErr.Code
+		payload.Message = appErr.Message
 	}
+	return transport.OutgoingMessage{Type: "ERROR", Payload: payload}
 }
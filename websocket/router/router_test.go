@@ -0,0 +1,89 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/thesrcielos/TopTankBattle/internal/apperrors"
+	"github.com/thesrcielos/TopTankBattle/internal/game"
+	"github.com/thesrcielos/TopTankBattle/websocket/message"
+	"github.com/thesrcielos/TopTankBattle/websocket/transport"
+)
+
+func captureReplies(t *testing.T) *[]transport.OutgoingMessage {
+	replies := []transport.OutgoingMessage{}
+	previous := sendToPlayer
+	sendToPlayer = func(playerId string, msg transport.OutgoingMessage) {
+		replies = append(replies, msg)
+	}
+	t.Cleanup(func() { sendToPlayer = previous })
+	return &replies
+}
+
+func TestRouteMessageSendsStartGameError(t *testing.T) {
+	replies := captureReplies(t)
+	gameService := game.NewMockGameService(t)
+	gameService.On("StartGame", "p1", "room1", false).
+		Return(apperrors.NewAppError(403, "Only the host can start the game", nil))
+
+	RouteMessage("p1", message.Message{
+		Type:      "GAME_START",
+		Payload:   json.RawMessage(`{"roomId":"room1"}`),
+		RequestId: "req-7",
+	}, gameService)
+
+	assert.Equal(t, []transport.OutgoingMessage{{
+		Type:    "ERROR",
+		Payload: message.ErrorPayload{Code: 403, Message: "Only the host can start the game", RequestId: "req-7"},
+	}}, *replies)
+}
+
+func TestRouteMessageAcknowledgesCommands(t *testing.T) {
+	replies := captureReplies(t)
+	gameService := game.NewMockGameService(t)
+	gameService.On("StartGame", "p1", "room1", false).Return(nil)
+
+	RouteMessage("p1", message.Message{
+		Type:      "GAME_START",
+		Payload:   json.RawMessage(`{"roomId":"room1"}`),
+		RequestId: "req-8",
+	}, gameService)
+
+	assert.Equal(t, []transport.OutgoingMessage{{
+		Type:    "ACK",
+		Payload: message.AckPayload{RequestId: "req-8", Type: "GAME_START"},
+	}}, *replies)
+}
+
+func TestRouteMessageDoesNotAcknowledgeWithoutRequestId(t *testing.T) {
+	replies := captureReplies(t)
+	gameService := game.NewMockGameService(t)
+	gameService.On("StartGame", "p1", "room1", false).Return(nil)
+	gameService.On("MovePlayer", "p1", mock.Anything).Return()
+
+	RouteMessage("p1", message.Message{Type: "GAME_START", Payload: json.RawMessage(`{"roomId":"room1"}`)}, gameService)
+	RouteMessage("p1", message.Message{Type: "MOVE", Payload: json.RawMessage(`{"x":1,"y":2}`), RequestId: "m1"}, gameService)
+
+	assert.Empty(t, *replies)
+}
+
+func TestRouteMessageRejectsUnknownAndInvalidMessages(t *testing.T) {
+	replies := captureReplies(t)
+	gameService := game.NewMockGameService(t)
+
+	RouteMessage("p1", message.Message{Type: "DANCE", RequestId: "a"}, gameService)
+	RouteMessage("p1", message.Message{Type: "MOVE", Payload: json.RawMessage(`"north"`), RequestId: "b"}, gameService)
+
+	assert.Len(t, *replies, 2)
+	assert.Equal(t, message.ErrorPayload{Code: 400, Message: "Unknown message type DANCE", RequestId: "a"}, (*replies)[0].Payload)
+	assert.Equal(t, message.ErrorPayload{Code: 400, Message: "Invalid MOVE payload", RequestId: "b"}, (*replies)[1].Payload)
+}
+
+func TestErrorFrameHidesUnexpectedErrors(t *testing.T) {
+	frame := errorFrame("r1", errors.New("redis: connection refused"))
+
+	assert.Equal(t, message.ErrorPayload{Code: 500, Message: "Internal server error", RequestId: "r1"}, frame.Payload)
+}
@@ -1,9 +1,12 @@
 package transport
 
 import (
+	"encoding/json"
 	"log"
 
+	"github.com/gorilla/websocket"
 	"github.com/thesrcielos/TopTankBattle/internal/game/state"
+	"github.com/thesrcielos/TopTankBattle/websocket/message"
 )
 
 type OutgoingMessage struct {
@@ -11,18 +14,51 @@ type OutgoingMessage struct {
 	Payload interface{} `json:"payload"`
 }
 
+// positionTypes are the messages that only matter until a newer one for the same player is sent
+var positionTypes = map[string]bool{
+	"MOVE":      true,
+	"GAME_MOVE": true,
+}
+
+// SendToPlayer queues a message on the connection of a player, it never waits for the client
 func SendToPlayer(playerID string, msg OutgoingMessage) {
 	player := state.GetPlayer(playerID)
 	if player == nil {
 		return
 	}
 
-	player.ConnMu.Lock()
-	defer player.ConnMu.Unlock()
+	frameType, data, err := Encode(player.Protocol(), msg)
+	if err != nil {
+		log.Println("Error encoding msg to", playerID, ":", err)
+		return
+	}
+	player.Send(coalesceKey(msg), frameType, data)
+}
+
+// Encode writes a message in the subprotocol negotiated by the client, JSON by default
+func Encode(protocol string, msg OutgoingMessage) (int, []byte, error) {
+	if protocol == message.BinaryProtocol {
+		data, err := message.EncodeBinary(msg.Type, msg.Payload)
+		return websocket.BinaryMessage, data, err
+	}
+	data, err := json.Marshal(msg)
+	return websocket.TextMessage, data, err
+}
 
-	if err := player.Conn.WriteJSON(msg); err != nil {
-		log.Println("Error sending msg to", playerID, ":", err)
+// coalesceKey groups the position updates of a player so only the latest one waits in the queue
+func coalesceKey(msg OutgoingMessage) string {
+	if !positionTypes[msg.Type] {
+		return ""
+	}
+	payload, ok := msg.Payload.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	playerId, ok := payload["playerId"].(string)
+	if !ok {
+		return ""
 	}
+	return msg.Type + ":" + playerId
 }
 
 func BroadcastToPlayers(players *[]string, msg OutgoingMessage) {